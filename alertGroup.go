@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type groupedAlert struct {
+	alert        Alert
+	notification GotifyNotification
+	token        string
+}
+
+// groupsByToken splits the alerts of a webhook call by the Gotify token they are sent with,
+// which escalation steps or a plugin may have changed for single alerts. The order of the
+// alerts is kept within each group
+func groupsByToken(grouped []groupedAlert) [][]groupedAlert {
+	groups := [][]groupedAlert{}
+	index := map[string]int{}
+	for _, g := range grouped {
+		i, ok := index[g.token]
+		if !ok {
+			i = len(groups)
+			index[g.token] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], g)
+	}
+	return groups
+}
+
+// buildGroupNotification combines the individually rendered alerts of a webhook call into a
+// single notification. Firing alerts are listed before resolved ones, the highest priority of
+// all alerts wins and the extras of the first alert setting a given key are kept.
+func buildGroupNotification(grouped []groupedAlert) GotifyNotification {
+	var firing []groupedAlert
+	var resolved []groupedAlert
+	commonTitle := grouped[0].notification.Title
+	priority := grouped[0].notification.Priority
+	extras := make(map[string]interface{})
+
+	for _, g := range grouped {
+		if g.alert.Status == "resolved" {
+			resolved = append(resolved, g)
+		} else {
+			firing = append(firing, g)
+		}
+		if g.notification.Title != commonTitle {
+			commonTitle = ""
+		}
+		if g.notification.Priority > priority {
+			priority = g.notification.Priority
+		}
+		for key, value := range g.notification.Extras {
+			if _, ok := extras[key]; !ok {
+				extras[key] = value
+			}
+		}
+	}
+
+	/* Only a single alert is left - there is nothing to combine */
+	if len(grouped) == 1 {
+		return GotifyNotification{
+			Title:    grouped[0].notification.Title,
+			Message:  grouped[0].notification.Message,
+			Priority: priority,
+			Extras:   extras,
+		}
+	}
+
+	counts := []string{}
+	if len(firing) > 0 {
+		counts = append(counts, fmt.Sprintf("FIRING:%d", len(firing)))
+	}
+	if len(resolved) > 0 {
+		counts = append(counts, fmt.Sprintf("RESOLVED:%d", len(resolved)))
+	}
+	title := fmt.Sprintf("[%s] ", strings.Join(counts, ", "))
+	if commonTitle != "" {
+		title += commonTitle
+	} else {
+		title += fmt.Sprintf("%d alerts", len(grouped))
+	}
+
+	_, isMarkdown := extras["client::display"]
+	sections := []string{}
+	if len(firing) > 0 {
+		sections = append(sections, formatGroupSection("Firing", firing, isMarkdown))
+	}
+	if len(resolved) > 0 {
+		sections = append(sections, formatGroupSection("Resolved", resolved, isMarkdown))
+	}
+
+	return GotifyNotification{
+		Title:    title,
+		Message:  strings.Join(sections, "\n\n"),
+		Priority: priority,
+		Extras:   extras,
+	}
+}
+
+func formatGroupSection(heading string, alerts []groupedAlert, isMarkdown bool) string {
+	var b strings.Builder
+	if isMarkdown {
+		b.WriteString(fmt.Sprintf("**%s**\n", heading))
+	} else {
+		b.WriteString(fmt.Sprintf("%s:\n", heading))
+	}
+
+	for _, g := range alerts {
+		title := g.notification.Title
+		if isMarkdown {
+			title = fmt.Sprintf("**%s**", title)
+		}
+		b.WriteString(fmt.Sprintf("\n- %s", title))
+		if message := strings.TrimSpace(g.notification.Message); message != "" {
+			b.WriteString("\n  " + strings.ReplaceAll(message, "\n", "\n  "))
+		}
+	}
+	return b.String()
+}
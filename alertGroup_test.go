@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func groupTestAlert(status string, title string, message string, priority int, extras map[string]interface{}) groupedAlert {
+	return groupedAlert{
+		alert:        Alert{Status: status},
+		notification: GotifyNotification{Title: title, Message: message, Priority: priority, Extras: extras},
+	}
+}
+
+func TestBuildGroupNotification(t *testing.T) {
+	display := map[string]string{"contentType": "text/markdown"}
+
+	tests := []struct {
+		name    string
+		grouped []groupedAlert
+		want    GotifyNotification
+	}{
+		{
+			name:    "single alert",
+			grouped: []groupedAlert{groupTestAlert("firing", "Disk full", "95% used", 5, nil)},
+			want:    GotifyNotification{Title: "Disk full", Message: "95% used", Priority: 5, Extras: map[string]interface{}{}},
+		},
+		{
+			name: "common title",
+			grouped: []groupedAlert{
+				groupTestAlert("firing", "Disk full", "web1", 5, nil),
+				groupTestAlert("firing", "Disk full", "web2", 8, nil),
+			},
+			want: GotifyNotification{
+				Title:    "[FIRING:2] Disk full",
+				Message:  "Firing:\n\n- Disk full\n  web1\n- Disk full\n  web2",
+				Priority: 8,
+				Extras:   map[string]interface{}{},
+			},
+		},
+		{
+			name: "resolved listed after firing",
+			grouped: []groupedAlert{
+				groupTestAlert("resolved", "CPU high", "back to normal", 2, nil),
+				groupTestAlert("firing", "Disk full", "line one\nline two", 5, nil),
+				groupTestAlert("firing", "Memory low", "", 3, nil),
+			},
+			want: GotifyNotification{
+				Title:    "[FIRING:2, RESOLVED:1] 3 alerts",
+				Message:  "Firing:\n\n- Disk full\n  line one\n  line two\n- Memory low\n\nResolved:\n\n- CPU high\n  back to normal",
+				Priority: 5,
+				Extras:   map[string]interface{}{},
+			},
+		},
+		{
+			name: "markdown and first extras win",
+			grouped: []groupedAlert{
+				groupTestAlert("resolved", "CPU high", "ok", 2, map[string]interface{}{"client::display": display, "key": "first"}),
+				groupTestAlert("resolved", "Disk full", "ok", 2, map[string]interface{}{"key": "second", "other": 1}),
+			},
+			want: GotifyNotification{
+				Title:    "[RESOLVED:2] 2 alerts",
+				Message:  "**Resolved**\n\n- **CPU high**\n  ok\n- **Disk full**\n  ok",
+				Priority: 2,
+				Extras:   map[string]interface{}{"client::display": display, "key": "first", "other": 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildGroupNotification(tt.grouped)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildGroupNotification() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+/* Alerts escalated to another application are sent as a group of their own */
+func TestGroupsByToken(t *testing.T) {
+	grouped := []groupedAlert{
+		{alert: Alert{Fingerprint: "a"}, token: "escalated"},
+		{alert: Alert{Fingerprint: "b"}, token: "default"},
+		{alert: Alert{Fingerprint: "c"}, token: "escalated"},
+		{alert: Alert{Fingerprint: "d"}, token: "other"},
+	}
+
+	groups := groupsByToken(grouped)
+	fingerprints := [][]string{}
+	for _, group := range groups {
+		names := []string{}
+		for _, g := range group {
+			names = append(names, g.alert.Fingerprint)
+		}
+		fingerprints = append(fingerprints, names)
+	}
+	if want := [][]string{{"a", "c"}, {"b"}, {"d"}}; !reflect.DeepEqual(fingerprints, want) {
+		t.Errorf("groupsByToken() = %v, want %v in the order of their first alert", fingerprints, want)
+	}
+
+	if groups := groupsByToken(nil); len(groups) != 0 {
+		t.Errorf("groupsByToken(nil) = %v", groups)
+	}
+}
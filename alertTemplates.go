@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+)
+
+// alertTemplateConfig gives alerts whose alertname matches a title and message of their own,
+// taking the place of the title and message annotations
+type alertTemplateConfig struct {
+	Alertname       string `yaml:"alertname"`
+	Title           string `yaml:"title"`
+	Message         string `yaml:"message"`
+	ResolvedTitle   string `yaml:"resolved_title"`
+	ResolvedMessage string `yaml:"resolved_message"`
+}
+
+type alertTemplate struct {
+	alertTemplateConfig
+	alertname *regexp.Regexp
+}
+
+// parseAlertTemplates compiles the alertname patterns of the templates of the config file.
+// Patterns are anchored regular expressions, just like =~ matchers of Alertmanager
+func parseAlertTemplates(configs []alertTemplateConfig) ([]alertTemplate, error) {
+	templates := []alertTemplate{}
+	for _, cfg := range configs {
+		if cfg.Alertname == "" {
+			return nil, fmt.Errorf("alertname is required for templates")
+		}
+		if cfg.Title == "" && cfg.Message == "" && cfg.ResolvedTitle == "" && cfg.ResolvedMessage == "" {
+			return nil, fmt.Errorf("template of alertname '%s' has neither title nor message", cfg.Alertname)
+		}
+		re, err := regexp.Compile("^(?:" + cfg.Alertname + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid alertname pattern '%s': %w", cfg.Alertname, err)
+		}
+		templates = append(templates, alertTemplate{alertTemplateConfig: cfg, alertname: re})
+	}
+	return templates, nil
+}
+
+// loadAlertTemplates reads the templates of --config_file, exiting when they are invalid
+func loadAlertTemplates(path string) []alertTemplate {
+	cfg, err := loadBridgeConfig(path)
+	var templates []alertTemplate
+	if err == nil {
+		templates, err = parseAlertTemplates(cfg.Templates)
+	}
+	if err != nil {
+		slog.Error("Invalid templates in config file", "error", err)
+		os.Exit(1)
+	}
+	return templates
+}
+
+// alertTemplate returns the first template of the config file matching the alertname of the
+// alert, or nil when there is none
+func (svr *bridge) alertTemplate(alert Alert) *alertTemplate {
+	for i := range svr.alertTemplates {
+		if svr.alertTemplates[i].alertname.MatchString(alert.Labels["alertname"]) {
+			return &svr.alertTemplates[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type alertmanagerAlert struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      struct {
+		State       string   `json:"state"`
+		SilencedBy  []string `json:"silencedBy"`
+		InhibitedBy []string `json:"inhibitedBy"`
+	} `json:"status"`
+}
+
+// suppressedFingerprints asks the Alertmanager v2 API for all alerts that are currently
+// silenced or inhibited and returns their fingerprints
+func (svr *bridge) suppressedFingerprints(ctx context.Context) (map[string]bool, error) {
+	endpoint := strings.TrimSuffix(*svr.alertmanagerURL, "/") + "/api/v2/alerts?active=false&unprocessed=false&silenced=true&inhibited=true"
+	/* Shares the connections of the gotify client */
+	client := http.Client{
+		Transport: svr.gotifyClient.Transport,
+		Timeout:   *svr.timeout,
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("non-200 response from alertmanager at %s: %s", endpoint, resp.Status)
+	}
+
+	var alerts []alertmanagerAlert
+	if err = json.Unmarshal(body, &alerts); err != nil {
+		return nil, fmt.Errorf("invalid JSON returned from alertmanager: %w", err)
+	}
+
+	suppressed := make(map[string]bool)
+	for _, a := range alerts {
+		if a.Status.State == "suppressed" || len(a.Status.SilencedBy) > 0 || len(a.Status.InhibitedBy) > 0 {
+			suppressed[a.Fingerprint] = true
+		}
+	}
+	return suppressed, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// amqpInput consumes a queue of RabbitMQ and dispatches every message like a webhook call to
+// path. Messages are acknowledged once dispatched and returned to the queue otherwise, so the
+// broker keeps them until Gotify can be reached
+type amqpInput struct {
+	url      string
+	queue    string
+	path     string
+	config   amqp.Config
+	prefetch int
+	backoff  time.Duration
+}
+
+// amqpInputFromFlags creates the RabbitMQ input configured by the --amqp_* flags
+func amqpInputFromFlags() (*amqpInput, error) {
+	var tlsConfig *tls.Config
+	if *amqpCAFile != "" {
+		var err error
+		if tlsConfig, err = clientTLSConfig(*amqpCAFile, false); err != nil {
+			return nil, err
+		}
+	}
+	return newAMQPInput(*amqpURL, *amqpQueue, *amqpPath, tlsConfig)
+}
+
+func newAMQPInput(rawURL string, queue string, path string, tlsConfig *tls.Config) (*amqpInput, error) {
+	if _, err := amqp.ParseURI(rawURL); err != nil {
+		return nil, fmt.Errorf("invalid AMQP URL: %w", err)
+	}
+	if queue == "" {
+		return nil, fmt.Errorf("the queue is required")
+	}
+	config := amqp.Config{TLSClientConfig: tlsConfig, Properties: amqp.NewConnectionProperties()}
+	config.Properties.SetClientConnectionName("alertmanager_gotify_bridge")
+	return &amqpInput{url: rawURL, queue: queue, path: path, config: config, prefetch: 10, backoff: 10 * time.Second}, nil
+}
+
+func (a *amqpInput) run(svr *bridge) {
+	target, err := svr.pathBridge(a.path)
+	if err != nil {
+		slog.Error("Invalid --amqp_path", "error", err)
+		os.Exit(1)
+	}
+	server := a.url
+	if u, err := url.Parse(a.url); err == nil {
+		server = u.Redacted()
+	}
+	log := slog.With("queue", a.queue)
+
+	/* The library doesn't reconnect, the queue is consumed again on a new connection instead */
+	for {
+		if err := a.consume(log, target); err != nil {
+			log.Warn("Unable to consume from RabbitMQ - reconnecting", "server", server, "error", err)
+		}
+		time.Sleep(a.backoff)
+	}
+}
+
+// consume dispatches the messages of the queue until the connection is lost
+func (a *amqpInput) consume(log *slog.Logger, target *bridge) error {
+	conn, err := amqp.DialConfig(a.url, a.config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	channel, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err = channel.Qos(a.prefetch, 0, false); err != nil {
+		return err
+	}
+	deliveries, err := channel.Consume(a.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	log.Info("Consuming alerts from RabbitMQ", "format", *target.inputFormat)
+
+	for delivery := range deliveries {
+		deliveryLog := log.With("delivery_tag", delivery.DeliveryTag, "redelivered", delivery.Redelivered)
+		if target.consumeMessage(deliveryLog, delivery.Body) {
+			/* Requeued messages arrive again right away, waiting keeps from hammering Gotify */
+			time.Sleep(a.backoff)
+			err = delivery.Nack(false, true)
+		} else {
+			err = delivery.Ack(false)
+		}
+		if err != nil {
+			deliveryLog.Warn("Unable to settle message - it may be dispatched again", "error", err)
+		}
+	}
+	select {
+	case err := <-closed:
+		if err != nil {
+			return err
+		}
+	default:
+	}
+	return fmt.Errorf("the broker cancelled the consumer")
+}
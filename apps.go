@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+const appTokenPrefix = "GOTIFY_APP_TOKEN_"
+
+// parseAppTokens collects the named Gotify application tokens given as environment
+// variables GOTIFY_APP_TOKEN_<NAME>. Names are matched case insensitively
+func parseAppTokens(environ []string) map[string]string {
+	tokens := make(map[string]string)
+	for _, env := range environ {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], appTokenPrefix) || parts[1] == "" {
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimPrefix(parts[0], appTokenPrefix))
+		if name != "" {
+			tokens[name] = parts[1]
+		}
+	}
+	return tokens
+}
+
+// appName returns the application selected by the path of a webhook request, e.g. infra
+// for /gotify_webhook/infra. The name is empty for requests to the webhook path itself
+func (svr *bridge) appName(r *http.Request) string {
+	return strings.ToLower(strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(*svr.webhookPath, "/")), "/"))
+}
+
+// appPath is the path pattern routing requests for named applications to the webhook handler
+func (svr *bridge) appPath() string {
+	return strings.TrimSuffix(*svr.webhookPath, "/") + "/"
+}
+
+// keyToken resolves the key other tools send in place of the Gotify token. The key is either
+// the name of an application of GOTIFY_APP_TOKEN_<NAME> or a token itself, while an empty key
+// stands for the default token
+func (svr *bridge) keyToken(key string) string {
+	if key == "" {
+		return svr.gotifyToken.Get()
+	}
+	if named, ok := svr.appTokens[strings.ToLower(key)]; ok {
+		return named
+	}
+	return key
+}
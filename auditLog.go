@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* Suffix of rotated audit logs, sorting in the order they were rotated */
+const auditRotatedFormat = "20060102T150405.000"
+
+// auditRecord is a line of the audit log, describing the outcome of processing a single alert
+type auditRecord struct {
+	Time         time.Time              `json:"time"`
+	Outcome      string                 `json:"outcome"`
+	Fingerprint  string                 `json:"fingerprint,omitempty"`
+	Status       string                 `json:"status"`
+	StartsAt     string                 `json:"starts_at,omitempty"`
+	EndsAt       string                 `json:"ends_at,omitempty"`
+	Labels       map[string]string      `json:"labels"`
+	Annotations  map[string]string      `json:"annotations"`
+	Title        string                 `json:"title"`
+	Message      string                 `json:"message"`
+	Priority     int                    `json:"priority"`
+	Extras       map[string]interface{} `json:"extras,omitempty"`
+	GotifyStatus int                    `json:"gotify_status,omitempty"`
+	MessageID    int                    `json:"message_id,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// auditLog appends a JSON record per processed alert to a file. The file is rotated once it
+// grows beyond maxSize or is older than interval, keeping the given number of rotated files
+type auditLog struct {
+	path     string
+	maxSize  int64
+	interval time.Duration
+	backups  int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func openAuditLog(path string, maxSize int64, interval time.Duration, backups int) (*auditLog, error) {
+	a := &auditLog{path: path, maxSize: maxSize, interval: interval, backups: backups}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+/* Opens the file for appending, the caller holds the lock */
+func (a *auditLog) open() error {
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	a.file, a.size, a.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+// write appends a record, rotating the file first when it is due
+func (a *auditLog) write(record auditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tooLarge := a.maxSize > 0 && a.size > 0 && a.size+int64(len(line)) > a.maxSize
+	tooOld := a.interval > 0 && time.Since(a.opened) >= a.interval
+	if tooLarge || tooOld {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	return err
+}
+
+/* Moves the current file aside and starts a new one, the caller holds the lock */
+func (a *auditLog) rotate() error {
+	a.file.Close()
+	if err := os.Rename(a.path, a.path+"."+time.Now().Format(auditRotatedFormat)); err != nil {
+		/* Keep writing to the old file rather than losing records */
+		if openErr := a.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	if a.backups <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(a.path + ".*")
+	if err != nil {
+		return err
+	}
+	/* Only files named like rotated ones are removed, whatever else lies next to the log stays */
+	rotated := []string{}
+	for _, match := range matches {
+		if _, err := time.Parse(auditRotatedFormat, strings.TrimPrefix(match, a.path+".")); err == nil {
+			rotated = append(rotated, match)
+		}
+	}
+	sort.Strings(rotated)
+	for len(rotated) > a.backups {
+		os.Remove(rotated[0])
+		rotated = rotated[1:]
+	}
+	return nil
+}
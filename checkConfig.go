@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/exporter-toolkit/web"
+)
+
+// configCheck collects the results of check-config, printing one line per check
+type configCheck struct {
+	problems int
+}
+
+func (c *configCheck) report(what string, err error) {
+	if err != nil {
+		c.problems++
+		fmt.Printf("FAIL  %s: %s\n", what, err)
+		return
+	}
+	fmt.Printf("OK    %s\n", what)
+}
+
+func (c *configCheck) skip(what string, reason string) {
+	fmt.Printf("SKIP  %s: %s\n", what, reason)
+}
+
+// runCheckConfig implements the check-config command. It validates the flags, environment,
+// config file and templates without contacting Gotify or Vault and returns the exit code: 0
+// when no problems were found, 1 otherwise
+func runCheckConfig() int {
+	c := &configCheck{}
+	validateConfig(c.report)
+	c.checkTemplates()
+
+	if *configFile == "" {
+		c.skip("config file", "--config_file not set")
+	} else if c.problems > 0 {
+		c.skip("config file", "fix the problems above first")
+	} else {
+		c.checkConfigFile()
+	}
+
+	if c.problems > 0 {
+		fmt.Printf("\n%d problem(s) found\n", c.problems)
+		return 1
+	}
+	fmt.Printf("\nConfiguration is valid\n")
+	return 0
+}
+
+// validateConfig checks the flags and environment without contacting Gotify, Vault, Redis or
+// message brokers and reports the outcome of every check. Both setupBridge and check-config
+// rely on it, so check-config accepts exactly the configurations the bridge starts with
+func validateConfig(report func(what string, err error)) {
+	report("gotify endpoint "+*gotifyEndpoint, checkURL(normalizeEndpoint(*gotifyEndpoint)))
+	report("gotify token", checkToken())
+	if os.Getenv("GOTIFY_CLIENT_TOKEN") == "" && (*onResolve != "new" || *provisionApps != "off") {
+		report("gotify client token", errors.New("GOTIFY_CLIENT_TOKEN must be set for --on_resolve and --provision_apps"))
+	}
+	if *vaultSecretPath != "" {
+		report("vault "+*vaultAddress, checkVault())
+	}
+
+	_, err := newGotifyTransport(*gotifyProxyURL, *gotifyCAFile, *gotifyInsecure, *gotifyMaxIdleConns)
+	report("gotify connection settings", err)
+	report("input format "+*inputFormat, checkMainInputFormat(*inputFormat))
+
+	targets, err := parseGotifyTargets(*gotifyTargets)
+	report(fmt.Sprintf("gotify targets (%d)", len(targets)), err)
+
+	if *simplePath != "" && !strings.HasPrefix(*simplePath, "/") {
+		report("simple path "+*simplePath, errors.New("--simple_path must start with /"))
+	}
+	if !*disableHealth && *healthInterval <= 0 {
+		report("gotify health probe", errors.New("--gotify_health_interval must be positive, use --disable_gotify_health to disable the health probe"))
+	}
+	if *alertmanagerURL != "" {
+		report("alertmanager API URL "+*alertmanagerURL, checkURL(*alertmanagerURL))
+	}
+	if *jwtJWKSURL != "" {
+		report("JWKS "+*jwtJWKSURL, checkJWT())
+	}
+
+	if *minPriority > *maxPriority {
+		report("priority range", fmt.Errorf("--min_priority %d must not be above --max_priority %d", *minPriority, *maxPriority))
+	}
+	_, err = severityPriorityMap(*severityPriority)
+	report("severity priorities", err)
+	_, err = includeDetailSections(*includeDetails)
+	report("included details", err)
+	_, err = newStatusDecorations(*statusPrefix, *statusEmoji, *statusColor)
+	report("status decorations", err)
+	_, err = loadExtendedDetailsTemplate(*extendedTemplate)
+	report("extended details template", err)
+	_, err = urlRewrites(*urlRewriteRules)
+	report(fmt.Sprintf("URL rewrite rules (%d)", len(*urlRewriteRules)), err)
+	_, err = time.LoadLocation(*displayTimezone)
+	report("display timezone "+*displayTimezone, err)
+
+	if *skipResolved && *resolvedOnly {
+		report("status filter", errors.New("only one of --skip_resolved and --resolved_only may be set"))
+	}
+	if *groupAlerts && *onResolve != "new" {
+		report("resolved messages", errors.New("--on_resolve=delete and --on_resolve=append can't be used with --group_alerts"))
+	}
+	_, err = parseMatcherSets(*ignoreMatchers)
+	report(fmt.Sprintf("ignore matchers (%d)", len(*ignoreMatchers)), err)
+	_, err = parseMatcherSets(*onlyMatchers)
+	report(fmt.Sprintf("only matchers (%d)", len(*onlyMatchers)), err)
+	if *watchdogMatcher != "" {
+		_, err = newWatchdog(*watchdogMatcher, *watchdogTimeout, *watchdogPriority)
+		report("watchdog", err)
+	}
+	if len(*quietHoursWindows) > 0 {
+		_, err = parseQuietHours(*quietHoursWindows, *quietHoursTimezone, *quietHoursPriority, *quietHoursAction)
+		report(fmt.Sprintf("quiet hours (%d)", len(*quietHoursWindows)), err)
+	}
+	if len(*escalationSteps) > 0 {
+		_, err = parseEscalation(*escalationSteps, parseAppTokens(os.Environ()))
+		report(fmt.Sprintf("escalation steps (%d)", len(*escalationSteps)), err)
+	}
+
+	if *renotifyInterval > 0 && *renotifyForgetAfter <= 0 {
+		report("re-notification", errors.New("--renotify_forget_after must be positive"))
+	}
+	if *relayAlerts && *relayGroupWait <= 0 {
+		report("alert relay", errors.New("--relay_group_wait must be positive"))
+	}
+	if *dedupRedisAddress != "" {
+		_, err = newDeduplicator(*dedupRedisAddress, os.Getenv("DEDUP_REDIS_PASSWORD"), *dedupRedisDB, *dedupRedisTLS, *dedupTTL, *timeout)
+		report("deduplication "+*dedupRedisAddress, err)
+	}
+	if *smtpAddress != "" {
+		_, err = newSMTPServer(*smtpAddress, *smtpSenders, *smtpMaxSize)
+		report("SMTP server "+*smtpAddress, err)
+	}
+	if *mqttBroker != "" {
+		_, err = mqttInputFromFlags()
+		report("MQTT broker "+redactString(*mqttBroker), err)
+	}
+	if len(*kafkaBrokers) > 0 {
+		_, err = kafkaInputFromFlags()
+		report("Kafka brokers "+strings.Join(*kafkaBrokers, ","), err)
+	}
+	if *natsURL != "" {
+		_, err = natsInputFromFlags()
+		report("NATS server "+redactString(*natsURL), err)
+	}
+	if *amqpURL != "" {
+		_, err = amqpInputFromFlags()
+		report("RabbitMQ server "+redactString(*amqpURL), err)
+	}
+	if *stormThreshold > 0 {
+		_, err = newStormCollapse(*stormThreshold, *stormWindow, *stormPriority)
+		report("storm collapse", err)
+	}
+	if *flapThreshold > 0 {
+		_, err = newFlapDetector(*flapThreshold, *flapWindow, *flapAction)
+		report("flapping detection", err)
+	}
+	if *pluginPath != "" {
+		_, err = newAlertPlugin(*pluginPath, *pluginTimeout)
+		report("plugin "+*pluginPath, err)
+	}
+	if *scriptPath != "" {
+		_, err = newAlertScript(*scriptPath, *scriptTimeout)
+		report("script "+*scriptPath, err)
+	}
+	if *dispatchHookCommand != "" {
+		_, err = newDispatchHook(*dispatchHookCommand, *dispatchHookTimeout)
+		report("dispatch hook "+*dispatchHookCommand, err)
+	}
+	if len(*forwardURLs) > 0 {
+		_, err = newWebhookForwarder(*forwardURLs, *forwardTimeout)
+		report(fmt.Sprintf("forward URLs (%d)", len(*forwardURLs)), err)
+	}
+
+	if *enablePprof && *metricsPort == 0 && (*authUsername == "" || authPassword == "") {
+		report("profiling", errors.New("--enable_pprof requires --metrics_auth_username and its password or a separate --metrics_port"))
+	}
+	if *webConfigFile != "" {
+		report("web config file "+*webConfigFile, web.Validate(*webConfigFile))
+	}
+}
+
+func checkURL(raw string) error {
+	u, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	return nil
+}
+
+/* The token may come from the environment, a file or vault - only the first two can be checked offline */
+func checkToken() error {
+	if *gotifyTokenFile != "" {
+		_, err := readTokenFile(*gotifyTokenFile)
+		return err
+	}
+	if *vaultSecretPath != "" || os.Getenv("GOTIFY_TOKEN") != "" {
+		return nil
+	}
+	return errors.New("set GOTIFY_TOKEN, --gotify_token_file or --vault_secret_path")
+}
+
+/* Vault itself is only asked for the token at startup */
+func checkVault() error {
+	if err := checkURL(*vaultAddress); err != nil {
+		return err
+	}
+	if *vaultSecretKey == "" {
+		return errors.New("--vault_secret_key must not be empty")
+	}
+	if *vaultRefresh <= 0 {
+		return errors.New("--vault_refresh_interval must be positive")
+	}
+	_, err := newVaultClient(*vaultAddress, *vaultRole, *vaultAuthPath)
+	return err
+}
+
+func checkJWT() error {
+	if err := checkURL(*jwtJWKSURL); err != nil {
+		return err
+	}
+	if *jwtRefresh <= 0 {
+		return errors.New("--jwt_jwks_refresh must be positive")
+	}
+	return nil
+}
+
+// checkTemplates parses the user-defined templates and renders the templates given as flags
+// for a firing and a resolved sample alert
+func (c *configCheck) checkTemplates() {
+	if _, err := os.Stat(tmplMsgPath); err != nil {
+		c.skip("user-defined templates", fmt.Sprintf("%s not found", tmplMsgPath))
+	} else {
+		_, err = parseUserTemplates(tmplMsgPath)
+		c.report("user-defined templates in "+tmplMsgPath, err)
+	}
+
+	flags := []struct {
+		name   string
+		value  string
+		status string
+	}{
+		{"--resolved_title_template", *resolvedTitle, "resolved"},
+		{"--resolved_message_template", *resolvedMessage, "resolved"},
+		{"--priority_template", *priorityTemplate, "firing"},
+	}
+	for _, flag := range flags {
+		if flag.value == "" {
+			continue
+		}
+		_, err := renderTemplate(flag.value, sampleAlert(flag.status), nil)
+		c.report("template "+flag.name, err)
+	}
+}
+
+func (c *configCheck) checkConfigFile() {
+	cfg, err := loadBridgeConfig(*configFile)
+	c.report("config file "+*configFile, err)
+	if err != nil {
+		return
+	}
+
+	templates, err := parseAlertTemplates(cfg.Templates)
+	c.report("templates of config file", err)
+	if err != nil {
+		return
+	}
+	for _, tmpl := range templates {
+		for _, t := range []struct{ name, value, status string }{
+			{"title", tmpl.Title, "firing"},
+			{"message", tmpl.Message, "firing"},
+			{"resolved_title", tmpl.ResolvedTitle, "resolved"},
+			{"resolved_message", tmpl.ResolvedMessage, "resolved"},
+		} {
+			if t.value == "" {
+				continue
+			}
+			_, err := renderTemplate(t.value, sampleAlert(t.status), nil)
+			c.report(fmt.Sprintf("template %s of alertname %s", t.name, tmpl.Alertname), err)
+		}
+	}
+
+	_, err = newInhibitor(cfg.InhibitRules)
+	c.report(fmt.Sprintf("inhibit rules (%d)", len(cfg.InhibitRules)), err)
+	if err != nil {
+		return
+	}
+
+	svr := newBridge(nil)
+	for _, ep := range cfg.Endpoints {
+		_, err := svr.endpointBridge(ep)
+		if err == nil && ep.GotifyEndpoint != "" {
+			err = checkURL(normalizeEndpoint(ep.GotifyEndpoint))
+		}
+		c.report("endpoint "+ep.Path, err)
+	}
+}
+
+func sampleAlert(status string) Alert {
+	now := time.Now().UTC()
+	return Alert{
+		Status:      status,
+		Labels:      map[string]string{"alertname": "CheckConfig", *severityLabel: "warning", "instance": "localhost:9090"},
+		Annotations: map[string]string{*titleAnnotation: "Sample alert", *messageAnnotation: "Rendered by check-config"},
+		StartsAt:    now.Add(-time.Hour).Format(time.RFC3339),
+		EndsAt:      now.Format(time.RFC3339),
+		Fingerprint: "check_config",
+	}
+}
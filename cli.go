@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	serveCmd = kingpin.Command("serve", "Run the bridge. This is the default when no command is given").Default()
+
+	renderCmd             = kingpin.Command("render", "Render the alerts of a webhook payload the same way the bridge would and print the resulting Gotify messages, without a running Alertmanager or Gotify")
+	renderPayload         = renderCmd.Flag("payload", "File holding the Alertmanager webhook JSON to render. Use - to read from stdin").Required().String()
+	renderTitleTemplate   = renderCmd.Flag("title_template", "Template to render the title from instead of the title annotation").String()
+	renderMessageTemplate = renderCmd.Flag("message_template", "Template to render the message from instead of the message annotation").String()
+	renderToken           = renderCmd.Flag("token", "Gotify application token used to select user-defined templates ($GOTIFY_TOKEN)").Envar("GOTIFY_TOKEN").String()
+
+	checkConfigCmd = kingpin.Command("check-config", "Validate the flags, environment, config file and templates without contacting Gotify, print a summary and exit with 1 when there are problems")
+
+	sendTestCmd    = kingpin.Command("send-test", "Send a synthetic alert through the bridge to the configured Gotify endpoint(s) to verify the token, network path and formatting")
+	sendTestStatus = sendTestCmd.Flag("status", "Status of the test alert").Default("firing").Enum("firing", "resolved")
+)
+
+// runRender implements the render command and returns the exit code: 0 when all alerts
+// rendered, 1 otherwise
+func runRender() int {
+	var b []byte
+	var err error
+	if *renderPayload == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(*renderPayload)
+	}
+	if err != nil {
+		slog.Error("Unable to read payload", "error", err)
+		return 1
+	}
+
+	userTemplates, err := parseUserTemplates(tmplMsgPath)
+	if err != nil {
+		slog.Debug("Falling back to default alerting", "error", err)
+	}
+	svr := newBridge(userTemplates)
+
+	notification, err := svr.parseNotification(b)
+	if err != nil {
+		slog.Error("Unmarshal of payload failed", "error", err)
+		return 1
+	}
+	notification.shareGroupFields()
+	svr.rewriteURLs(&notification)
+
+	exitCode := 0
+	for idx, alert := range notification.Alerts {
+		alert.Annotations = withTemplateOverrides(alert.Annotations)
+
+		outbound, proceed, err := svr.renderAlert(slog.With("alert", idx), alert, *renderToken, b)
+		fmt.Printf("--- Alert %d (%s)\n", idx, alert.Status)
+		if err != nil {
+			fmt.Printf("Error:    %s\n", err)
+			exitCode = 1
+		}
+		if !proceed {
+			fmt.Printf("Not dispatched\n\n")
+			continue
+		}
+
+		extras, _ := json.Marshal(outbound.Extras)
+		fmt.Printf("Title:    %s\nPriority: %d\nExtras:   %s\nMessage:\n%s\n\n", outbound.Title, outbound.Priority, extras, outbound.Message)
+	}
+	return exitCode
+}
+
+/* Copies the annotations so the templates given on the command line take their place */
+func withTemplateOverrides(annotations map[string]string) map[string]string {
+	result := make(map[string]string, len(annotations)+2)
+	for key, value := range annotations {
+		result[key] = value
+	}
+	if *renderTitleTemplate != "" {
+		result[*titleAnnotation] = *renderTitleTemplate
+	}
+	if *renderMessageTemplate != "" {
+		result[*messageAnnotation] = *renderMessageTemplate
+	}
+	return result
+}
+
+// runSendTest implements the send-test command and returns the exit code: 0 when gotify
+// accepted the test alert, 1 otherwise
+func runSendTest(svr *bridge) int {
+	messageID, err := svr.sendTestAlert(*sendTestStatus)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Printf("Test alert dispatched to gotify as message %d\n", messageID)
+	return 0
+}
+
+// sendTestAlert sends a synthetic alert with the given status to gotify and returns the ID of
+// the created message
+func (svr *bridge) sendTestAlert(status string) (int, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	alert := Alert{
+		Status: status,
+		Labels: map[string]string{
+			"alertname":    "AlertmanagerGotifyBridgeTest",
+			*severityLabel: "info",
+		},
+		Annotations: map[string]string{
+			*titleAnnotation:   "Test notification from alertmanager_gotify_bridge",
+			*messageAnnotation: fmt.Sprintf("This is a test alert sent by alertmanager_gotify_bridge %s at %s", Version, now),
+		},
+		StartsAt:    now,
+		Fingerprint: "alertmanager_gotify_bridge_test",
+	}
+	b, _ := json.Marshal(Notification{Alerts: []Alert{alert}})
+
+	logger := slog.With("alert", 0)
+	outbound, proceed, err := svr.renderAlert(logger, alert, svr.gotifyToken.Get(), b)
+	if err != nil || !proceed {
+		return 0, fmt.Errorf("Unable to render test alert: %v", err)
+	}
+
+	statusCode, gotifyStatus, messageID, err := svr.dispatch(context.Background(), logger, svr.gotifyToken.Get(), outbound)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to reach gotify: %w", err)
+	}
+	if statusCode != 200 {
+		return 0, fmt.Errorf("Gotify rejected the test alert: %s", gotifyStatus)
+	}
+	return messageID, nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// endpointConfig declares an additional webhook endpoint. Settings that are not given are
+// taken from the command line flags
+type endpointConfig struct {
+	Path               string         `yaml:"path"`
+	Listen             string         `yaml:"listen"`
+	Format             string         `yaml:"format"`
+	Generic            *genericConfig `yaml:"generic"`
+	GotifyEndpoint     string         `yaml:"gotify_endpoint"`
+	TokenEnv           string         `yaml:"token_env"`
+	TitleAnnotation    *string        `yaml:"title_annotation"`
+	MessageAnnotation  *string        `yaml:"message_annotation"`
+	PriorityAnnotation *string        `yaml:"priority_annotation"`
+	DefaultPriority    *int           `yaml:"default_priority"`
+	ExtendedDetails    *bool          `yaml:"extended_details"`
+}
+
+type bridgeConfig struct {
+	Endpoints    []endpointConfig      `yaml:"endpoints"`
+	Templates    []alertTemplateConfig `yaml:"templates"`
+	InhibitRules []inhibitRuleConfig   `yaml:"inhibit_rules"`
+}
+
+func loadBridgeConfig(path string) (*bridgeConfig, error) {
+	cfg := &bridgeConfig{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// endpointBridge creates a copy of the bridge with the settings of an endpoint applied. State
+// such as metrics and the message store stays shared with the original bridge
+func (svr *bridge) endpointBridge(cfg endpointConfig) (*bridge, error) {
+	if !strings.HasPrefix(cfg.Path, "/") {
+		return nil, fmt.Errorf("path '%s' of endpoint must start with /", cfg.Path)
+	}
+
+	e := *svr
+	path := cfg.Path
+	e.webhookPath = &path
+
+	if cfg.Format != "" {
+		if err := checkInputFormat(cfg.Format); err != nil {
+			return nil, fmt.Errorf("invalid format of endpoint %s: %w", cfg.Path, err)
+		}
+		format := cfg.Format
+		e.inputFormat = &format
+	}
+	if cfg.Generic != nil {
+		generic, err := newGenericInput(*cfg.Generic)
+		if err != nil {
+			return nil, fmt.Errorf("invalid generic setting of endpoint %s: %w", cfg.Path, err)
+		}
+		e.genericInput = generic
+	}
+	if *e.inputFormat == "generic" && e.genericInput == nil {
+		return nil, fmt.Errorf("endpoint %s with format generic needs the generic setting", cfg.Path)
+	}
+
+	if cfg.GotifyEndpoint != "" {
+		endpoint := normalizeEndpoint(cfg.GotifyEndpoint)
+		if _, err := url.ParseRequestURI(endpoint); err != nil {
+			return nil, fmt.Errorf("invalid gotify endpoint of endpoint %s: %w", cfg.Path, err)
+		}
+		e.gotifyEndpoint = &endpoint
+		if e.provisioner != nil {
+			e.provisioner = newAppProvisioner(&e, e.provisioner.perReceiver)
+		}
+	}
+
+	if cfg.TokenEnv != "" {
+		token := os.Getenv(cfg.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("the token for endpoint %s must be set in the environment variable %s", cfg.Path, cfg.TokenEnv)
+		}
+		e.gotifyToken = newTokenSource(token)
+	}
+
+	if cfg.TitleAnnotation != nil {
+		e.titleAnnotation = cfg.TitleAnnotation
+	}
+	if cfg.MessageAnnotation != nil {
+		e.messageAnnotation = cfg.MessageAnnotation
+	}
+	if cfg.PriorityAnnotation != nil {
+		e.priorityAnnotation = cfg.PriorityAnnotation
+	}
+	if cfg.DefaultPriority != nil {
+		e.defaultPriority = cfg.DefaultPriority
+	}
+	if cfg.ExtendedDetails != nil {
+		e.extendedDetails = cfg.ExtendedDetails
+	}
+	return &e, nil
+}
+
+// serveEndpoints registers the endpoints of --config_file. Endpoints without a listen address
+// are served by mainMux, all others by an additional server per address. Exits when the
+// configuration is invalid
+func (svr *bridge) serveEndpoints(mainMux *http.ServeMux) {
+	cfg, err := loadBridgeConfig(*configFile)
+	if err != nil {
+		slog.Error("Invalid config file", "error", err)
+		os.Exit(1)
+	}
+
+	muxes := map[string]*http.ServeMux{}
+	registered := map[string]bool{*svr.webhookPath: true, *metricsPath: true}
+	if svr.servesApps() {
+		registered[svr.appPath()] = true
+	}
+	if svr.relay != nil {
+		registered[relayPath] = true
+	}
+	if *pushoverAPI {
+		registered[pushoverPath] = true
+	}
+	if *pagerdutyAPI {
+		registered[pagerdutyPath] = true
+	}
+	if *simplePath != "" {
+		registered[*simplePath] = true
+	}
+
+	register := func(listen string, mux *http.ServeMux, path string, handler http.HandlerFunc) {
+		if registered[listen+path] {
+			slog.Error("Endpoint path is used more than once", "listen", listen, "path", path)
+			os.Exit(1)
+		}
+		registered[listen+path] = true
+		mux.HandleFunc(path, handler)
+	}
+
+	for _, ep := range cfg.Endpoints {
+		epSvr, err := svr.endpointBridge(ep)
+		if err != nil {
+			slog.Error("Invalid endpoint in config file", "error", err)
+			os.Exit(1)
+		}
+
+		mux := mainMux
+		if ep.Listen != "" {
+			if muxes[ep.Listen] == nil {
+				muxes[ep.Listen] = http.NewServeMux()
+			}
+			mux = muxes[ep.Listen]
+		}
+
+		register(ep.Listen, mux, ep.Path, epSvr.inputHandler(epSvr.handleCall))
+		if epSvr.servesApps() && epSvr.appPath() != ep.Path {
+			register(ep.Listen, mux, epSvr.appPath(), epSvr.inputHandler(epSvr.handleCall))
+		}
+		slog.Info("Serving additional endpoint", "listen", ep.Listen, "path", ep.Path, "gotify_endpoint", *epSvr.gotifyEndpoint)
+	}
+
+	for listen, mux := range muxes {
+		go func(listen string, mux *http.ServeMux) {
+			listener, err := net.Listen("tcp", listen)
+			if err == nil {
+				err = serveWeb(newHTTPServer(listen, mux), listener, *webConfigFile)
+			}
+			slog.Error("Error starting the server", "listen", listen, "error", err)
+			os.Exit(1)
+		}(listen, mux)
+	}
+}
+
+// pathBridge returns the bridge handling webhook calls to path, which is either the main
+// webhook path or the path of an endpoint of --config_file
+func (svr *bridge) pathBridge(path string) (*bridge, error) {
+	if path == "" || path == *svr.webhookPath {
+		return svr, nil
+	}
+	cfg, err := loadBridgeConfig(*configFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, ep := range cfg.Endpoints {
+		if ep.Path == path {
+			return svr.endpointBridge(ep)
+		}
+	}
+	return nil, fmt.Errorf("neither --webhook_path nor an endpoint of --config_file has the path %s", path)
+}
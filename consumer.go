@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// consumeMessage dispatches a message read from a broker like a webhook call with its body.
+// It reports whether the message should be delivered again because it could not be sent to
+// Gotify. Invalid messages are dropped, delivering them again wouldn't change anything
+func (svr *bridge) consumeMessage(log *slog.Logger, body []byte) bool {
+	metrics.Inc("requests_received")
+	id := newRequestID()
+	ctx := context.WithValue(context.Background(), requestIDKey{}, id)
+	log = log.With("request_id", id)
+
+	notification, err := svr.parseNotification(body)
+	if err != nil {
+		log.Warn("Dropping invalid message", "error", err, "body", string(body))
+		metrics.Inc("requests_invalid")
+		return false
+	}
+
+	respCode, text := svr.deliver(ctx, svr.gotifyToken.Get(), notification, body)
+	switch {
+	case respCode == http.StatusTooManyRequests || respCode >= http.StatusInternalServerError:
+		log.Warn("Unable to dispatch message - it will be delivered again", "status", respCode, "error", strings.Join(text, "; "))
+		return true
+	case respCode >= http.StatusBadRequest:
+		log.Warn("Dropping message that could not be dispatched", "status", respCode, "error", strings.Join(text, "; "))
+	}
+	return false
+}
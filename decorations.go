@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+/* Prefixes of the title used unless --status_prefix overrides them */
+var defaultStatusPrefixes = map[string]string{"firing": "[FIR]", "resolved": "[RES]"}
+
+// statusDecorations holds the prefix, emoji and color shown with the status of an alert. Each is
+// keyed by the status, or by the status and severity as STATUS/SEVERITY which takes precedence
+type statusDecorations struct {
+	prefixes map[string]string
+	emojis   map[string]string
+	colors   map[string]string
+}
+
+// parseStatusDecorations creates the decorations from --status_prefix, --status_emoji and
+// --status_color, exiting when a key names an unknown status
+func parseStatusDecorations(prefixes, emojis, colors map[string]string) *statusDecorations {
+	decorations, err := newStatusDecorations(prefixes, emojis, colors)
+	if err != nil {
+		slog.Error("Invalid status decoration", "error", err)
+		os.Exit(1)
+	}
+	return decorations
+}
+
+func newStatusDecorations(prefixes, emojis, colors map[string]string) (*statusDecorations, error) {
+	d := &statusDecorations{
+		prefixes: make(map[string]string, len(defaultStatusPrefixes)+len(prefixes)),
+		emojis:   emojis,
+		colors:   colors,
+	}
+	for status, prefix := range defaultStatusPrefixes {
+		d.prefixes[status] = prefix
+	}
+	for key, prefix := range prefixes {
+		d.prefixes[key] = prefix
+	}
+
+	for _, values := range []map[string]string{prefixes, emojis, colors} {
+		for key := range values {
+			status, _, _ := strings.Cut(key, "/")
+			if status != "firing" && status != "resolved" {
+				return nil, fmt.Errorf("unknown status '%s' in '%s' - expected firing or resolved, optionally followed by /SEVERITY", status, key)
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *statusDecorations) pick(values map[string]string, status string, severity string) string {
+	if value, ok := values[status+"/"+severity]; ok && severity != "" {
+		return value
+	}
+	return values[status]
+}
+
+// statusTitle is put in front of the title of an alert, e.g. "🔥 [FIR] "
+func (svr *bridge) statusTitle(alert Alert) string {
+	severity := alert.Labels[*svr.severityLabel]
+	parts := []string{}
+	for _, values := range []map[string]string{svr.decorations.emojis, svr.decorations.prefixes} {
+		if value := svr.decorations.pick(values, alert.Status, severity); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, " ") + " "
+}
+
+// statusLine is the bold status shown above Markdown messages, preceded by the emoji and
+// colored when a color is set for the status and severity of the alert
+func (svr *bridge) statusLine(alert Alert) string {
+	if alert.Status == "" {
+		return ""
+	}
+	severity := alert.Labels[*svr.severityLabel]
+
+	line := "**" + strings.ToUpper(alert.Status) + "**"
+	if color := svr.decorations.pick(svr.decorations.colors, alert.Status, severity); color != "" {
+		line = fmt.Sprintf(`<span style="color:%s">%s</span>`, color, line)
+	}
+	if emoji := svr.decorations.pick(svr.decorations.emojis, alert.Status, severity); emoji != "" {
+		line = emoji + " " + line
+	}
+	return line
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// deduplicator lets only one of several replicas of the bridge dispatch an alert. Alertmanager
+// sends every notification to all replicas behind a load balancer or listed as receivers, so
+// the replicas race to claim each alert in a shared Redis
+type deduplicator struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func newDeduplicator(address string, password string, db int, useTLS bool, ttl time.Duration, timeout time.Duration) (*deduplicator, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis address '%s': %w", address, err)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("the deduplication TTL must be positive")
+	}
+
+	options := &redis.Options{
+		Addr:         address,
+		Password:     password,
+		DB:           db,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+	}
+	if useTLS {
+		options.TLSConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	}
+	return &deduplicator{client: redis.NewClient(options), ttl: ttl}, nil
+}
+
+// dedupKey identifies an alert of a notification group in a status. Resolved notifications
+// get their own key, so they are sent even though the firing one was claimed before
+func dedupKey(alert Alert) string {
+	if alert.Fingerprint == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(alert.GroupKey + "\x00" + alert.Fingerprint + "\x00" + alert.Status))
+	return "alertmanager_gotify_bridge:dedup:" + hex.EncodeToString(sum[:16])
+}
+
+// claim reports whether this replica should dispatch the alert. It is always true when
+// deduplication is disabled or the alert can't be identified
+func (d *deduplicator) claim(ctx context.Context, key string) (bool, error) {
+	if d == nil || key == "" {
+		return true, nil
+	}
+	/* SET NX only succeeds for the first replica, the others find the key held */
+	claimed, err := d.client.SetNX(ctx, key, "1", d.ttl).Result()
+	if err != nil {
+		return true, err
+	}
+	return claimed, nil
+}
+
+// release gives up the claim on an alert that was not dispatched, so another replica may
+// send it when Alertmanager retries the notification
+func (d *deduplicator) release(ctx context.Context, key string) error {
+	if d == nil || key == "" {
+		return nil
+	}
+	return d.client.Del(ctx, key).Err()
+}
+
+func (svr *bridge) releaseClaim(ctx context.Context, logger *slog.Logger, key string) {
+	if err := svr.dedup.release(context.WithoutCancel(ctx), key); err != nil {
+		logger.Warn("Unable to release the claim on the alert - other replicas won't retry it", "error", err)
+	}
+}
+
+// failed keeps a notification gotify did not accept for replay. The replica keeps its claims
+// on the alerts while it replays them, as releasing them would let another replica send them
+// as well when Alertmanager retries. Without replay the claims are released instead
+func (svr *bridge) failed(ctx context.Context, logger *slog.Logger, h heldMessage) {
+	if svr.replay.keep(h) {
+		return
+	}
+	if h.group == nil {
+		svr.releaseClaim(ctx, logger, dedupKey(h.alert))
+	}
+	for _, g := range h.group {
+		svr.releaseClaim(ctx, logger, dedupKey(g.alert))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestDeduplicator(t *testing.T) (*deduplicator, *miniredis.Miniredis) {
+	t.Helper()
+	redis := miniredis.RunT(t)
+	d, err := newDeduplicator(redis.Addr(), "", 0, false, time.Minute, time.Second)
+	if err != nil {
+		t.Fatalf("newDeduplicator() error = %v", err)
+	}
+	return d, redis
+}
+
+func TestDeduplicatorClaim(t *testing.T) {
+	ctx := context.Background()
+	replica1, redis := newTestDeduplicator(t)
+	replica2, _ := newDeduplicator(redis.Addr(), "", 0, false, time.Minute, time.Second)
+
+	firing := dedupKey(Alert{GroupKey: "{}:{alertname=\"Disk\"}", Fingerprint: "a1", Status: "firing"})
+	resolved := dedupKey(Alert{GroupKey: "{}:{alertname=\"Disk\"}", Fingerprint: "a1", Status: "resolved"})
+
+	if claimed, err := replica1.claim(ctx, firing); !claimed || err != nil {
+		t.Fatalf("first claim = %v, %v, want true", claimed, err)
+	}
+	if claimed, _ := replica2.claim(ctx, firing); claimed {
+		t.Error("second replica claimed an alert that was claimed already")
+	}
+	if claimed, _ := replica2.claim(ctx, resolved); !claimed {
+		t.Error("resolved notification was not claimed after the firing one")
+	}
+	if ttl := redis.TTL(firing); ttl != time.Minute {
+		t.Errorf("claim expires after %s, want 1m", ttl)
+	}
+
+	redis.FastForward(time.Minute)
+	if claimed, _ := replica2.claim(ctx, firing); !claimed {
+		t.Error("alert was not claimed again after the TTL")
+	}
+}
+
+func TestDeduplicatorRelease(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newTestDeduplicator(t)
+	key := dedupKey(Alert{Fingerprint: "a1", Status: "firing"})
+
+	d.claim(ctx, key)
+	if err := d.release(ctx, key); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+	if claimed, _ := d.claim(ctx, key); !claimed {
+		t.Error("released alert could not be claimed again")
+	}
+}
+
+func TestDeduplicatorUnidentified(t *testing.T) {
+	var disabled *deduplicator
+	if claimed, err := disabled.claim(context.Background(), "key"); !claimed || err != nil {
+		t.Errorf("claim() without deduplication = %v, %v, want true", claimed, err)
+	}
+
+	d, _ := newTestDeduplicator(t)
+	if key := dedupKey(Alert{Status: "firing"}); key != "" {
+		t.Fatalf("dedupKey() of an alert without fingerprint = %q", key)
+	}
+	for i := 0; i < 2; i++ {
+		if claimed, _ := d.claim(context.Background(), ""); !claimed {
+			t.Error("alert without fingerprint was deduplicated")
+		}
+	}
+}
+
+func TestDeduplicatorRedisDown(t *testing.T) {
+	d, redis := newTestDeduplicator(t)
+	redis.Close()
+
+	claimed, err := d.claim(context.Background(), dedupKey(Alert{Fingerprint: "a1"}))
+	if err == nil || !claimed {
+		t.Errorf("claim() with Redis down = %v, %v, want true and an error", claimed, err)
+	}
+}
+
+/* A failed alert stays claimed while it waits for replay, or is released without replay */
+func TestFailedReleasesClaim(t *testing.T) {
+	ctx := context.Background()
+	alert := Alert{Fingerprint: "a1", Status: "firing"}
+	key := dedupKey(alert)
+
+	for _, replaySize := range []int{0, 10} {
+		d, redis := newTestDeduplicator(t)
+		svr := &bridge{dedup: d, replay: newReplayQueue(replaySize, 0, "")}
+		d.claim(ctx, key)
+
+		svr.failed(ctx, slog.Default(), heldMessage{svr: svr, alert: alert})
+
+		if held := redis.Exists(key); held != (replaySize > 0) {
+			t.Errorf("replay_size=%d: claim held = %v", replaySize, held)
+		}
+		if queued := svr.replay.count(); queued != min(replaySize, 1) {
+			t.Errorf("replay_size=%d: %d notifications queued", replaySize, queued)
+		}
+	}
+
+	d, redis := newTestDeduplicator(t)
+	svr := &bridge{dedup: d, replay: newReplayQueue(0, 0, "")}
+	group := []groupedAlert{{alert: Alert{Fingerprint: "g1", Status: "firing"}}, {alert: Alert{Fingerprint: "g2", Status: "firing"}}}
+	for _, g := range group {
+		d.claim(ctx, dedupKey(g.alert))
+	}
+	svr.failed(ctx, slog.Default(), heldMessage{svr: svr, group: group})
+	if keys := redis.Keys(); len(keys) != 0 {
+		t.Errorf("claims of the failed group not released: %v", keys)
+	}
+}
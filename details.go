@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+var detailSections = []string{"labels", "annotations", "values"}
+
+// parseIncludeDetails splits the comma separated sections of --include_details, exiting when
+// a section is unknown
+func parseIncludeDetails(spec string) []string {
+	sections, err := includeDetailSections(spec)
+	if err != nil {
+		slog.Error("Invalid --include_details", "error", err)
+		os.Exit(1)
+	}
+	return sections
+}
+
+func includeDetailSections(spec string) ([]string, error) {
+	sections := []string{}
+	for _, section := range strings.Split(spec, ",") {
+		section = strings.ToLower(strings.TrimSpace(section))
+		if section == "" {
+			continue
+		}
+		if !contains(detailSections, section) {
+			return nil, fmt.Errorf("unknown section '%s' - expected any of %s", section, strings.Join(detailSections, ","))
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+// includedDetails renders the sections of --include_details for an alert, as Markdown tables
+// or as indented plain text. The annotations the title and message were taken from are
+// left out, and sections without entries are skipped
+func (svr *bridge) includedDetails(alert Alert, asMarkdown bool) string {
+	parts := []string{}
+	for _, section := range svr.includeDetails {
+		var column string
+		var entries map[string]string
+		switch section {
+		case "labels":
+			column, entries = "Label", alert.Labels
+		case "annotations":
+			column, entries = "Annotation", map[string]string{}
+			for key, value := range alert.Annotations {
+				if key != *svr.titleAnnotation && key != *svr.messageAnnotation {
+					entries[key] = value
+				}
+			}
+		case "values":
+			column, entries = "Sample", valueEntries(alert)
+		}
+
+		if len(entries) == 0 {
+			continue
+		}
+		if asMarkdown {
+			parts = append(parts, markdownTable(column, entries))
+		} else {
+			parts = append(parts, plainTable(column, entries))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+/* The samples of the value string keyed by their variable, or by their metric and labels */
+func valueEntries(alert Alert) map[string]string {
+	values, err := alert.Values()
+	if err != nil || len(values) == 0 {
+		return nil
+	}
+
+	entries := make(map[string]string, len(values))
+	for _, v := range values {
+		key := v.Var
+		if key == "" {
+			labels := make([]string, 0, len(v.Labels))
+			for _, name := range sortedKeys(v.Labels) {
+				labels = append(labels, fmt.Sprintf("%s=%q", name, v.Labels[name]))
+			}
+			key = fmt.Sprintf("%s{%s}", v.Metric, strings.Join(labels, ", "))
+		}
+		entries[key] = alert.Humanize(v.Value)
+	}
+	return entries
+}
+
+func plainTable(keyColumn string, entries map[string]string) string {
+	var b strings.Builder
+	b.WriteString(keyColumn + "s:")
+	for _, key := range sortedKeys(entries) {
+		b.WriteString(fmt.Sprintf("\n  %s: %s", key, entries[key]))
+	}
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// discordMessage is the payload of a Discord webhook. Components, polls and attachments are
+// ignored as Gotify can't show them
+type discordMessage struct {
+	Content  string         `json:"content"`
+	Username string         `json:"username"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	Color       *int   `json:"color"`
+	Timestamp   string `json:"timestamp"`
+	Author      *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"author"`
+	Fields []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"fields"`
+	Image *struct {
+		URL string `json:"url"`
+	} `json:"image"`
+	Footer *struct {
+		Text string `json:"text"`
+	} `json:"footer"`
+}
+
+// parseDiscord converts the message of a Discord webhook into a firing alert. Discord already
+// speaks Markdown, only its mentions, timestamps and spoilers are resolved. The color of the
+// first colored embed sets the severity and priority
+func parseDiscord(svr *bridge, body []byte) (Notification, error) {
+	/* Discord also accepts the JSON form encoded as the payload_json parameter */
+	if form, err := url.ParseQuery(string(body)); err == nil && form.Has("payload_json") {
+		body = []byte(form.Get("payload_json"))
+	}
+	var msg discordMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return Notification{}, fmt.Errorf("invalid Discord message: %w", err)
+	}
+
+	title, image := "", ""
+	var color *int
+	var startsAt time.Time
+	parts := []string{discordMarkdown(msg.Content)}
+	for _, embed := range msg.Embeds {
+		if color == nil {
+			color = embed.Color
+		}
+		if image == "" && embed.Image != nil {
+			image = embed.Image.URL
+		}
+		if stamp, err := time.Parse(time.RFC3339, embed.Timestamp); err == nil && startsAt.IsZero() {
+			startsAt = stamp
+		}
+
+		if embed.Author != nil && embed.Author.Name != "" {
+			if embed.Author.URL != "" {
+				parts = append(parts, fmt.Sprintf("*[%s](%s)*", embed.Author.Name, embed.Author.URL))
+			} else {
+				parts = append(parts, "*"+embed.Author.Name+"*")
+			}
+		}
+		if embed.Title != "" {
+			if title == "" {
+				title = embed.Title
+			}
+			if embed.URL != "" {
+				parts = append(parts, fmt.Sprintf("**[%s](%s)**", embed.Title, embed.URL))
+			} else {
+				parts = append(parts, "**"+embed.Title+"**")
+			}
+		}
+		parts = append(parts, discordMarkdown(embed.Description))
+		fields := []string{}
+		for _, field := range embed.Fields {
+			fields = append(fields, fmt.Sprintf("**%s**: %s", discordMarkdown(field.Name), discordMarkdown(field.Value)))
+		}
+		parts = append(parts, strings.Join(fields, "  \n"))
+		if embed.Footer != nil {
+			parts = append(parts, discordMarkdown(embed.Footer.Text))
+		}
+	}
+
+	message := joinParts(parts)
+	if message == "" {
+		return Notification{}, fmt.Errorf("invalid Discord message: neither content nor embeds are set")
+	}
+	if title == "" {
+		title = msg.Username
+	}
+	if title == "" {
+		title = "Discord"
+	}
+	if startsAt.IsZero() {
+		startsAt = time.Now()
+	}
+
+	alert := Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "Discord"},
+		Annotations: map[string]string{*svr.titleAnnotation: title, *svr.messageAnnotation: message},
+		StartsAt:    startsAt.UTC().Format(time.RFC3339),
+	}
+	if msg.Username != "" {
+		alert.Labels["username"] = msg.Username
+	}
+	if *extrasAnnotation != "" {
+		alert.Annotations[*extrasAnnotation+"::client::display::contentType"] = "text/markdown"
+	}
+	if image != "" && *imageAnnotation != "" {
+		alert.Annotations[*imageAnnotation] = image
+	}
+	if color != nil {
+		severity, priority := colorPriority(*color>>16&0xff, *color>>8&0xff, *color&0xff)
+		alert.Labels[*svr.severityLabel] = severity
+		svr.inputPriority(alert, priority)
+	}
+
+	return singleAlert("discord", "discord:"+alert.Labels["alertname"], alert), nil
+}
+
+var (
+	discordMention   = regexp.MustCompile(`<(@[!&]?|#)(\d+)>`)
+	discordTimestamp = regexp.MustCompile(`<t:(-?\d+)(?::[tTdDfFR])?>`)
+	discordSpoiler   = regexp.MustCompile(`\|\|(.+?)\|\|`)
+	discordEmoji     = regexp.MustCompile(`<a?(:\w+:)\d+>`)
+)
+
+// discordMarkdown resolves what only Discord understands: mentions become @id or #id,
+// timestamps are shown like displayTime does, custom emojis are shown by name and spoilers
+// are revealed
+func discordMarkdown(s string) string {
+	s = discordMention.ReplaceAllStringFunc(s, func(match string) string {
+		parts := discordMention.FindStringSubmatch(match)
+		return parts[1][:1] + parts[2]
+	})
+	s = discordTimestamp.ReplaceAllStringFunc(s, func(match string) string {
+		seconds, err := strconv.ParseInt(discordTimestamp.FindStringSubmatch(match)[1], 10, 64)
+		if err != nil {
+			return match
+		}
+		return displayTime(time.Unix(seconds, 0))
+	})
+	s = discordEmoji.ReplaceAllString(s, "$1")
+	return discordSpoiler.ReplaceAllString(s, "$1")
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiscordMarkdown(t *testing.T) {
+	since := displayTime(time.Unix(1700000000, 0))
+	for _, tt := range [][2]string{
+		{"**Disk** is full", "**Disk** is full"},
+		{"ping <@123456>", "ping @123456"},
+		{"ping <@!123456>", "ping @123456"},
+		{"ping <@&987>", "ping @987"},
+		{"in <#555>", "in #555"},
+		{"<:fire:1234> hot <a:party:99>", ":fire: hot :party:"},
+		{"the cause is ||a typo|| again", "the cause is a typo again"},
+		{"since <t:1700000000:R>", "since " + since},
+		{"<t:1700000000>", since},
+		{"<@name>", "<@name>"},
+	} {
+		if got := discordMarkdown(tt[0]); got != tt[1] {
+			t.Errorf("discordMarkdown(%q) = %q, want %q", tt[0], got, tt[1])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+)
+
+/* Runs of the hook beyond this many wait, so an alert storm doesn't fork hundreds of processes */
+const dispatchHookConcurrency = 4
+
+// dispatchHook runs a command after every attempt to send an alert to Gotify, with the alert as
+// JSON on stdin and the outcome in BRIDGE_* environment variables. Runs don't hold up
+// dispatching, and their failures are only logged
+type dispatchHook struct {
+	command string
+	timeout time.Duration
+	slots   chan struct{}
+}
+
+func newDispatchHook(command string, timeout time.Duration) (*dispatchHook, error) {
+	if _, err := exec.LookPath(command); err != nil {
+		return nil, err
+	}
+	return &dispatchHook{command: command, timeout: timeout, slots: make(chan struct{}, dispatchHookConcurrency)}, nil
+}
+
+// fire runs the hook in the background for the outcome of an alert. Only outcomes of actually
+// sending to Gotify run the hook: dispatched, renotified and failed
+func (h *dispatchHook) fire(alert Alert, outbound GotifyNotification, outcome string, statusCode int, messageID int, err error) {
+	if h == nil || (outcome != "dispatched" && outcome != "renotified" && outcome != "failed") {
+		return
+	}
+
+	input, jsonErr := json.Marshal(alert)
+	if jsonErr != nil {
+		slog.Warn("Unable to encode alert for dispatch hook", "error", jsonErr)
+		return
+	}
+	env := append(os.Environ(),
+		"BRIDGE_OUTCOME="+outcome,
+		fmt.Sprintf("BRIDGE_GOTIFY_STATUS=%d", statusCode),
+		fmt.Sprintf("BRIDGE_MESSAGE_ID=%d", messageID),
+		"BRIDGE_ALERT_STATUS="+alert.Status,
+		"BRIDGE_ALERTNAME="+alert.Labels["alertname"],
+		"BRIDGE_FINGERPRINT="+alert.Fingerprint,
+		"BRIDGE_TITLE="+outbound.Title,
+		fmt.Sprintf("BRIDGE_PRIORITY=%d", outbound.Priority),
+	)
+	if err != nil {
+		env = append(env, "BRIDGE_ERROR="+err.Error())
+	}
+
+	go func() {
+		h.slots <- struct{}{}
+		defer func() { <-h.slots }()
+		h.run(input, env, alert.Fingerprint, outcome)
+	}()
+}
+
+func (h *dispatchHook) run(input []byte, env []string, fingerprint string, outcome string) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.command)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(input)
+	output, err := cmd.CombinedOutput()
+
+	logger := slog.With("command", h.command, "fingerprint", fingerprint, "outcome", outcome)
+	if err != nil {
+		logger.Warn("Dispatch hook failed", "error", err, "output", string(output))
+		metrics.Inc("dispatch_hook_errors")
+		return
+	}
+	logger.Debug("Dispatch hook ran", "output", string(output))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+/* Timezone of timestamps shown in messages, set from --display_timezone at startup */
+var displayLocation = time.UTC
+
+func setupDisplayTime(timezone string) error {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("invalid display timezone: %w", err)
+	}
+	displayLocation = location
+	return nil
+}
+
+// asTime converts the timestamps found in alerts and templates: RFC3339 strings as sent by
+// Alertmanager, times and Unix timestamps in seconds
+func asTime(i interface{}) (time.Time, error) {
+	switch v := i.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, fmt.Errorf("no time given")
+		}
+		return *v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time '%s': %w", v, err)
+		}
+		return t, nil
+	default:
+		f, err := convertToFloat(i)
+		if err != nil {
+			return time.Time{}, err
+		}
+		t, err := floatToTime(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return *t, nil
+	}
+}
+
+// formatTime shows a timestamp in the given layout and the timezone of --display_timezone
+func formatTime(layout string, i interface{}) (string, error) {
+	t, err := asTime(i)
+	if err != nil {
+		return "", err
+	}
+	return t.In(displayLocation).Format(layout), nil
+}
+
+// displayTime shows a timestamp with --time_format in the timezone of --display_timezone.
+// Strings that are no RFC3339 timestamp are shown unchanged
+func displayTime(i interface{}) string {
+	formatted, err := formatTime(*timeFormat, i)
+	if err != nil {
+		return fmt.Sprint(i)
+	}
+	return formatted
+}
+
+// since returns how long ago a timestamp was, rounded to seconds
+func since(i interface{}) (time.Duration, error) {
+	t, err := asTime(i)
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(t).Round(time.Second), nil
+}
+
+// duration returns the time between two timestamps, rounded to seconds
+func duration(start interface{}, end interface{}) (time.Duration, error) {
+	from, err := asTime(start)
+	if err != nil {
+		return 0, err
+	}
+	to, err := asTime(end)
+	if err != nil {
+		return 0, err
+	}
+	return to.Sub(from).Round(time.Second), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+/* Returned for a Content-Encoding the bridge can't decode */
+type unsupportedEncodingError struct {
+	encoding string
+}
+
+func (e *unsupportedEncodingError) Error() string {
+	return fmt.Sprintf("unsupported Content-Encoding %s", e.encoding)
+}
+
+// decodeBody decompresses a webhook body sent with Content-Encoding gzip or deflate. The
+// decompressed body is limited to limit bytes as well, so a small compressed request can't
+// expand into an arbitrarily large one. Unlimited when limit is 0
+func decodeBody(r *http.Request, body io.Reader, limit int64) (io.Reader, error) {
+	var decoded io.Reader
+	var err error
+
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		decoded, err = gzip.NewReader(body)
+	case "deflate":
+		decoded, err = zlib.NewReader(body)
+	default:
+		return nil, &unsupportedEncodingError{encoding: encoding}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress request body: %w", err)
+	}
+
+	if limit > 0 {
+		decoded = &limitedReader{r: decoded, remaining: limit, limit: limit}
+	}
+	return decoded, nil
+}
+
+// limitedReader fails with the same error as http.MaxBytesReader once more than limit bytes
+// were read, so oversized bodies are handled alike whether they were compressed or not
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, &http.MaxBytesError{Limit: l.limit}
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n - int(-l.remaining), &http.MaxBytesError{Limit: l.limit}
+	}
+	return n, err
+}
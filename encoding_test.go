@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		limit   int64
+		bufSize int
+		want    string
+		wantErr bool
+	}{
+		{name: "below limit", input: "hello", limit: 10, bufSize: 512, want: "hello"},
+		{name: "at limit", input: "hello", limit: 5, bufSize: 512, want: "hello"},
+		{name: "above limit", input: "hello world", limit: 5, bufSize: 512, want: "hello", wantErr: true},
+		{name: "above limit in small reads", input: "hello world", limit: 5, bufSize: 2, want: "hello", wantErr: true},
+		{name: "at limit in small reads", input: "hello", limit: 5, bufSize: 1, want: "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limitedReader{r: strings.NewReader(tt.input), remaining: tt.limit, limit: tt.limit}
+
+			var got []byte
+			var err error
+			buf := make([]byte, tt.bufSize)
+			for {
+				var n int
+				n, err = l.Read(buf)
+				got = append(got, buf[:n]...)
+				if err != nil {
+					break
+				}
+			}
+
+			var maxBytes *http.MaxBytesError
+			if tt.wantErr != errors.As(err, &maxBytes) {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != io.EOF {
+				t.Fatalf("Read() error = %v, want EOF", err)
+			}
+			if tt.wantErr && maxBytes.Limit != tt.limit {
+				t.Errorf("Read() error limit = %d, want %d", maxBytes.Limit, tt.limit)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	payload := `{"status":"firing"}`
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write([]byte(payload))
+	gw.Close()
+
+	var deflated bytes.Buffer
+	zw := zlib.NewWriter(&deflated)
+	zw.Write([]byte(payload))
+	zw.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		limit    int64
+		want     string
+		wantErr  bool
+		readErr  bool
+	}{
+		{name: "plain", encoding: "", body: []byte(payload), want: payload},
+		{name: "identity", encoding: "identity", body: []byte(payload), want: payload},
+		{name: "gzip", encoding: "gzip", body: gzipped.Bytes(), want: payload},
+		{name: "x-gzip", encoding: " X-GZIP ", body: gzipped.Bytes(), want: payload},
+		{name: "deflate", encoding: "deflate", body: deflated.Bytes(), want: payload},
+		{name: "gzip within limit", encoding: "gzip", body: gzipped.Bytes(), limit: int64(len(payload)), want: payload},
+		{name: "gzip above limit", encoding: "gzip", body: gzipped.Bytes(), limit: 5, readErr: true},
+		{name: "not gzip", encoding: "gzip", body: []byte(payload), wantErr: true},
+		{name: "unsupported", encoding: "br", body: []byte(payload), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/gotify_webhook", nil)
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			decoded, err := decodeBody(r, bytes.NewReader(tt.body), tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := io.ReadAll(decoded)
+			if (err != nil) != tt.readErr {
+				t.Fatalf("reading decoded body error = %v, wantErr %v", err, tt.readErr)
+			}
+			if !tt.readErr && string(got) != tt.want {
+				t.Errorf("decoded body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* Alerts not sent again for this long are assumed resolved without a resolved notification */
+const escalationForgetAfter = 24 * time.Hour
+
+// escalationStep raises the priority of alerts that are firing for longer than after and
+// optionally sends them with the token of another Gotify application
+type escalationStep struct {
+	after    time.Duration
+	priority int
+	app      string
+	token    string
+}
+
+type escalationState struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// escalator tracks since when the bridge receives each firing alert to escalate it step by step
+type escalator struct {
+	steps []escalationStep
+
+	mu     sync.Mutex
+	alerts map[string]*escalationState
+}
+
+// parseEscalation reads steps given as AFTER=PRIORITY or AFTER=PRIORITY,APP, e.g. 15m=8 or
+// 1h=10,oncall. APP names an application token given as GOTIFY_APP_TOKEN_<APP>
+func parseEscalation(specs []string, appTokens map[string]string) (*escalator, error) {
+	e := &escalator{alerts: map[string]*escalationState{}}
+	for _, spec := range specs {
+		after, rest, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid escalation step '%s' - expected AFTER=PRIORITY[,APP]", spec)
+		}
+
+		step := escalationStep{}
+		var err error
+		if step.after, err = time.ParseDuration(after); err != nil {
+			return nil, fmt.Errorf("invalid escalation step '%s': %w", spec, err)
+		}
+
+		priority, app, _ := strings.Cut(rest, ",")
+		if step.priority, err = strconv.Atoi(priority); err != nil {
+			return nil, fmt.Errorf("invalid priority in escalation step '%s'", spec)
+		}
+		if app != "" {
+			step.app = strings.ToLower(app)
+			if step.token = appTokens[step.app]; step.token == "" {
+				return nil, fmt.Errorf("the token of escalation step '%s' must be set in the environment variable %s%s", spec, appTokenPrefix, strings.ToUpper(app))
+			}
+		}
+		e.steps = append(e.steps, step)
+	}
+
+	sort.Slice(e.steps, func(i, j int) bool { return e.steps[i].after < e.steps[j].after })
+	return e, nil
+}
+
+// escalate returns the last step a firing alert reached, or nil when it reached none yet.
+// It is nil for all alerts when no escalation is configured
+func (e *escalator) escalate(alert Alert) *escalationStep {
+	if e == nil || alert.Status != "firing" || alert.Fingerprint == "" {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	for fingerprint, state := range e.alerts {
+		if now.Sub(state.lastSeen) > escalationForgetAfter {
+			delete(e.alerts, fingerprint)
+		}
+	}
+
+	state, ok := e.alerts[alert.Fingerprint]
+	if !ok {
+		state = &escalationState{firstSeen: now}
+		e.alerts[alert.Fingerprint] = state
+	}
+	state.lastSeen = now
+
+	var reached *escalationStep
+	for i := range e.steps {
+		if now.Sub(state.firstSeen) >= e.steps[i].after {
+			reached = &e.steps[i]
+		}
+	}
+	return reached
+}
+
+// raise returns the priority of an alert that reached the step. Steps never lower the priority
+// and never raise it above maxPriority
+func (step *escalationStep) raise(priority int, maxPriority int) int {
+	if step.priority <= priority {
+		return priority
+	}
+	return min(step.priority, maxPriority)
+}
+
+// forget starts over with an alert, e.g. because it resolved
+func (e *escalator) forget(fingerprint string) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.alerts, fingerprint)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+/* Pretends the alert first arrived the given time ago */
+func firingSince(e *escalator, fingerprint string, ago time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.alerts[fingerprint] = &escalationState{firstSeen: time.Now().Add(-ago), lastSeen: time.Now()}
+}
+
+func TestEscalationSteps(t *testing.T) {
+	e, err := parseEscalation([]string{"1h=10,oncall", "15m=8"}, map[string]string{"oncall": "oncall-token"})
+	if err != nil {
+		t.Fatalf("parseEscalation() error = %v", err)
+	}
+	alert := Alert{Fingerprint: "a1", Status: "firing"}
+
+	if step := e.escalate(alert); step != nil {
+		t.Errorf("new alert escalated to %+v", step)
+	}
+
+	firingSince(e, "a1", 20*time.Minute)
+	if step := e.escalate(alert); step == nil || step.priority != 8 || step.token != "" {
+		t.Errorf("alert firing for 20m escalated to %+v, want priority 8", step)
+	}
+
+	firingSince(e, "a1", 2*time.Hour)
+	if step := e.escalate(alert); step == nil || step.priority != 10 || step.app != "oncall" || step.token != "oncall-token" {
+		t.Errorf("alert firing for 2h escalated to %+v, want priority 10 for oncall", step)
+	}
+
+	if step := e.escalate(Alert{Fingerprint: "a1", Status: "resolved"}); step != nil {
+		t.Errorf("resolved alert escalated to %+v", step)
+	}
+	e.forget("a1")
+	if step := e.escalate(alert); step != nil {
+		t.Errorf("alert escalated to %+v after starting over", step)
+	}
+}
+
+func TestEscalationForgetsGoneAlerts(t *testing.T) {
+	e, _ := parseEscalation([]string{"15m=8"}, nil)
+	e.alerts["gone"] = &escalationState{firstSeen: time.Now().Add(-48 * time.Hour), lastSeen: time.Now().Add(-25 * time.Hour)}
+
+	if step := e.escalate(Alert{Fingerprint: "gone", Status: "firing"}); step != nil {
+		t.Errorf("alert not sent for longer than escalationForgetAfter escalated to %+v", step)
+	}
+}
+
+func TestEscalationRaise(t *testing.T) {
+	tests := []struct {
+		step     int
+		priority int
+		max      int
+		want     int
+	}{
+		{step: 8, priority: 5, max: 10, want: 8},
+		{step: 8, priority: 9, max: 10, want: 9},
+		{step: 10, priority: 5, max: 7, want: 7},
+		{step: 99, priority: 5, max: 10, want: 10},
+		{step: 8, priority: 9, max: 7, want: 9},
+	}
+
+	for _, tt := range tests {
+		step := escalationStep{priority: tt.step}
+		if got := step.raise(tt.priority, tt.max); got != tt.want {
+			t.Errorf("step to %d raise(%d, %d) = %d, want %d", tt.step, tt.priority, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestParseEscalation(t *testing.T) {
+	for _, spec := range []string{"15m", "soon=8", "15m=high", "1h=10,missing"} {
+		if _, err := parseEscalation([]string{spec}, nil); err == nil {
+			t.Errorf("parseEscalation(%q) accepted an invalid step", spec)
+		}
+	}
+
+	var disabled *escalator
+	if step := disabled.escalate(Alert{Fingerprint: "a1", Status: "firing"}); step != nil {
+		t.Errorf("alert escalated without escalation steps to %+v", step)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	/* Events a subscriber may lag behind before further events are dropped for it */
+	eventBuffer = 64
+	/* Comments sent while nothing happens, so proxies don't close idle streams */
+	eventKeepalive = 30 * time.Second
+)
+
+// eventBroker passes the outcome of every processed alert to the clients of /api/v1/events.
+// Publishing never blocks, clients too slow to keep up miss events instead
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan historyEntry]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: map[chan historyEntry]struct{}{}}
+}
+
+func (b *eventBroker) subscribe() chan historyEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events := make(chan historyEntry, eventBuffer)
+	b.subscribers[events] = struct{}{}
+	return events
+}
+
+func (b *eventBroker) unsubscribe(events chan historyEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, events)
+}
+
+func (b *eventBroker) publish(entry historyEntry) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers {
+		select {
+		case events <- entry:
+		default:
+			metrics.Inc("events_dropped")
+		}
+	}
+}
+
+// handleEvents streams the outcome of every processed alert as Server-Sent Events until the
+// client disconnects
+func (svr *bridge) handleEvents(w http.ResponseWriter, r *http.Request) {
+	broker := svr.history.events
+	controller := http.NewResponseController(w)
+	/* The stream lasts longer than any --server_write_timeout */
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		slog.Warn("Unable to stream events", "error", err)
+		return
+	}
+
+	events := broker.subscribe()
+	defer broker.unsubscribe(events)
+	slog.Debug("Events client connected", "remote_addr", r.RemoteAddr)
+	defer slog.Debug("Events client disconnected", "remote_addr", r.RemoteAddr)
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case entry := <-events:
+			data, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: alert\ndata: %s\n\n", data)
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	ut "text/template"
+)
+
+//go:embed extended_details.tmpl
+var defaultExtendedDetailsTemplate string
+
+/* The templates a layout of --extended_details_template must define */
+var extendedDetailsParts = []string{"title", "header", "footer"}
+
+// extendedDetailsData is what the layout of --extended_details is rendered from: the alert
+// along with its decorated status and the link to its generator
+type extendedDetailsData struct {
+	Alert
+	StatusTitle string
+	StatusLine  string
+	SourceURL   string
+}
+
+// parseExtendedDetailsTemplate reads the layout of --extended_details from the given file, or
+// uses the embedded default when no file is given. Exits when the layout is invalid
+func parseExtendedDetailsTemplate(path string) *ut.Template {
+	tmpl, err := loadExtendedDetailsTemplate(path)
+	if err != nil {
+		slog.Error("Invalid --extended_details_template", "error", err)
+		os.Exit(1)
+	}
+	return tmpl
+}
+
+func loadExtendedDetailsTemplate(path string) (*ut.Template, error) {
+	text := defaultExtendedDetailsTemplate
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", path, err)
+		}
+		text = string(raw)
+	}
+
+	tmpl, err := ut.New("extended_details").Funcs(fxns).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range extendedDetailsParts {
+		if tmpl.Lookup(part) == nil {
+			return nil, fmt.Errorf("template '%s' is not defined - expected %s", part, strings.Join(extendedDetailsParts, ", "))
+		}
+	}
+	return tmpl, nil
+}
+
+// extendedDetailsLayout renders the title prefix, the header and the footer --extended_details
+// puts around the message of an alert
+func (svr *bridge) extendedDetailsLayout(alert Alert) (title string, header string, footer string, err error) {
+	data := extendedDetailsData{Alert: alert}
+	if alert.Status == "firing" || alert.Status == "resolved" {
+		data.StatusTitle = svr.statusTitle(alert)
+		data.StatusLine = svr.statusLine(alert)
+	}
+	if strings.HasPrefix(alert.GeneratorURL, "http") {
+		data.SourceURL = alert.GeneratorURL
+	}
+
+	rendered := make([]string, len(extendedDetailsParts))
+	for i, part := range extendedDetailsParts {
+		buf := &bytes.Buffer{}
+		if err = svr.extendedTemplate.ExecuteTemplate(buf, part, data); err != nil {
+			return "", "", "", fmt.Errorf("error in extended details template: %w", err)
+		}
+		rendered[i] = buf.String()
+	}
+	return rendered[0], rendered[1], rendered[2], nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// annotationExtras collects the Gotify extras requested by the annotations of an alert. The
+// annotation named by --image_annotation sets the image shown with the notification, the
+// annotation named by --extras_annotation holds a JSON object of extras, while annotations
+// named <extras_annotation>::<namespace>::<key>[::<key>...] set a single value, e.g.
+// gotify_extras::client::notification::click::url. All values are rendered as templates first
+func annotationExtras(alert Alert, externalURL *url.URL) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	if raw, ok := alert.Annotations[*imageAnnotation]; ok && *imageAnnotation != "" {
+		imageURL, err := renderTemplate(raw, alert, externalURL)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", *imageAnnotation, err)
+		}
+		if imageURL = strings.TrimSpace(imageURL); imageURL != "" {
+			result["client::notification"] = map[string]interface{}{"bigImageUrl": imageURL}
+		}
+	}
+
+	if *extrasAnnotation == "" {
+		return result, nil
+	}
+
+	if raw, ok := alert.Annotations[*extrasAnnotation]; ok {
+		rendered, err := renderTemplate(raw, alert, externalURL)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", *extrasAnnotation, err)
+		}
+
+		var parsed map[string]interface{}
+		if err = json.Unmarshal([]byte(rendered), &parsed); err != nil {
+			return nil, fmt.Errorf("annotation %s is not a JSON object: %w", *extrasAnnotation, err)
+		}
+		mergeExtras(result, parsed)
+	}
+
+	prefix := *extrasAnnotation + "::"
+	for name, raw := range alert.Annotations {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		/* Extras are keyed by namespace::action, everything after that addresses nested objects */
+		path := strings.Split(strings.TrimPrefix(name, prefix), "::")
+		if len(path) < 3 {
+			return nil, fmt.Errorf("annotation %s must be in the form %s<namespace>::<action>::<key>", name, prefix)
+		}
+
+		rendered, err := renderTemplate(raw, alert, externalURL)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", name, err)
+		}
+
+		keys := append([]string{path[0] + "::" + path[1]}, path[2:]...)
+		var value interface{} = rendered
+		for i := len(keys) - 1; i > 0; i-- {
+			value = map[string]interface{}{keys[i]: value}
+		}
+		mergeExtras(result, map[string]interface{}{keys[0]: value})
+	}
+	return result, nil
+}
+
+// mergeExtras deeply merges src into dst. Nested objects are combined, any other value in src
+// replaces the one in dst
+func mergeExtras(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeExtras(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// normalizeExtras converts the extras built by the bridge into plain nested maps so they can be
+// merged with the extras of annotations
+func normalizeExtras(extras map[string]interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{})
+	if err = json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
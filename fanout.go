@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+type gotifyTarget struct {
+	name     string
+	endpoint string
+	token    string
+}
+
+type targetResult struct {
+	statusCode int
+	status     string
+	messageID  int
+	err        error
+}
+
+// normalizeEndpoint makes sure a gotify endpoint ends with the /message path
+func normalizeEndpoint(endpoint string) string {
+	if strings.HasSuffix(endpoint, "/message") {
+		return endpoint
+	}
+
+	toAdd := "/message"
+	if strings.HasSuffix(endpoint, "/") {
+		toAdd = "message"
+	}
+	slog.Warn("/message not at the end of the gotify endpoint. Automatically appending it.", "endpoint", endpoint, "new_endpoint", endpoint+toAdd)
+	return endpoint + toAdd
+}
+
+// parseGotifyTargets reads the additional targets given as NAME=URL. Tokens are never
+// passed on the command line, but read from GOTIFY_TOKEN_<NAME>
+func parseGotifyTargets(specs []string) ([]gotifyTarget, error) {
+	targets := []gotifyTarget{}
+	seen := map[string]bool{"default": true}
+
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid gotify target '%s' - expected NAME=URL", spec)
+		}
+
+		name := parts[0]
+		if seen[name] {
+			return nil, fmt.Errorf("gotify target name '%s' is used more than once", name)
+		}
+		seen[name] = true
+
+		endpoint := normalizeEndpoint(parts[1])
+		if _, err := url.ParseRequestURI(endpoint); err != nil {
+			return nil, fmt.Errorf("invalid endpoint for gotify target '%s': %w", name, err)
+		}
+
+		tokenVar := "GOTIFY_TOKEN_" + strings.ToUpper(name)
+		token := os.Getenv(tokenVar)
+		if token == "" {
+			return nil, fmt.Errorf("the token for gotify target '%s' must be set in the environment variable %s", name, tokenVar)
+		}
+
+		targets = append(targets, gotifyTarget{name: name, endpoint: endpoint, token: token})
+	}
+	return targets, nil
+}
+
+// fanOut posts the notification to the default gotify server and all additional targets at
+// the same time. The result of the default server is returned unless it failed and another
+// target succeeded - the alert is considered delivered as long as one target received it
+func (svr *bridge) fanOut(ctx context.Context, logger *slog.Logger, token string, msg []byte) (int, string, int, error) {
+	all := append([]gotifyTarget{{name: "default", endpoint: *svr.gotifyEndpoint, token: token}}, svr.targets...)
+	results := make([]targetResult, len(all))
+
+	var wg sync.WaitGroup
+	for i, target := range all {
+		wg.Add(1)
+		go func(i int, target gotifyTarget) {
+			defer wg.Done()
+			r := &results[i]
+			r.statusCode, r.status, r.messageID, r.err = svr.post(ctx, logger.With("target", target.name), target.endpoint, target.token, msg)
+		}(i, target)
+	}
+	wg.Wait()
+
+	best := 0
+	for i, target := range all {
+		if results[i].err == nil && results[i].statusCode == 200 {
+			metrics.IncTarget(target.name, "dispatched")
+			if results[best].err != nil || results[best].statusCode != 200 {
+				best = i
+			}
+		} else {
+			metrics.IncTarget(target.name, "failed")
+			logger.Warn("Dispatch to gotify target failed", "target", target.name)
+		}
+	}
+
+	r := results[best]
+	if best != 0 {
+		/* Message IDs are only meaningful on the default server */
+		r.messageID = 0
+	}
+	return r.statusCode, r.status, r.messageID, r.err
+}
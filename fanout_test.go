@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+/* A gotify server answering with status and a message ID, remembering the tokens it was sent */
+type fakeGotify struct {
+	*httptest.Server
+	status int
+	id     int
+
+	mu     sync.Mutex
+	tokens []string
+}
+
+func newFakeGotify(t *testing.T, status int, id int) *fakeGotify {
+	g := &fakeGotify{status: status, id: id}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		g.tokens = append(g.tokens, r.Header.Get("X-Gotify-Key"))
+		status := g.status
+		g.mu.Unlock()
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"id":%d}`, g.id)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *fakeGotify) answer(status int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status = status
+}
+
+func (g *fakeGotify) received() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string{}, g.tokens...)
+}
+
+/* A bridge dispatching to endpoint without any of the optional features */
+func newDispatchTestBridge(endpoint string) *bridge {
+	dryRun := false
+	severity := "severity"
+	endpoint += "/message"
+	return &bridge{
+		gotifyEndpoint: &endpoint,
+		gotifyClient:   &http.Client{},
+		dryRun:         &dryRun,
+		severityLabel:  &severity,
+		instruments:    NewBridgeInstruments("test"),
+	}
+}
+
+func TestFanOutTokens(t *testing.T) {
+	primary := newFakeGotify(t, 200, 11)
+	backup := newFakeGotify(t, 200, 22)
+	phone := newFakeGotify(t, 200, 33)
+
+	t.Setenv("GOTIFY_TOKEN_BACKUP", "backup-token")
+	t.Setenv("GOTIFY_TOKEN_PHONE", "phone-token")
+	targets, err := parseGotifyTargets([]string{"backup=" + backup.URL, "phone=" + phone.URL + "/message"})
+	if err != nil {
+		t.Fatalf("parseGotifyTargets() error = %v", err)
+	}
+
+	svr := newDispatchTestBridge(primary.URL)
+	svr.targets = targets
+	code, _, id, err := svr.dispatch(context.Background(), slog.Default(), "default-token", GotifyNotification{Title: "Disk full"})
+	if err != nil || code != 200 || id != 11 {
+		t.Errorf("dispatch() = %d, %d, %v, want 200 with message ID 11 of the default server", code, id, err)
+	}
+
+	for _, tt := range []struct {
+		server *fakeGotify
+		token  string
+	}{{primary, "default-token"}, {backup, "backup-token"}, {phone, "phone-token"}} {
+		if got := tt.server.received(); len(got) != 1 || got[0] != tt.token {
+			t.Errorf("target received tokens %v, want [%s]", got, tt.token)
+		}
+	}
+}
+
+func TestFanOutDefaultFailed(t *testing.T) {
+	primary := newFakeGotify(t, 500, 0)
+	backup := newFakeGotify(t, 200, 22)
+	t.Setenv("GOTIFY_TOKEN_BACKUP", "backup-token")
+
+	svr := newDispatchTestBridge(primary.URL)
+	svr.targets, _ = parseGotifyTargets([]string{"backup=" + backup.URL})
+
+	/* Delivered through the backup, but its message ID means nothing to the default server */
+	code, _, id, err := svr.dispatch(context.Background(), slog.Default(), "default-token", GotifyNotification{})
+	if err != nil || code != 200 || id != 0 {
+		t.Errorf("dispatch() = %d, %d, %v, want 200 without message ID", code, id, err)
+	}
+
+	backup.answer(503)
+	code, _, _, _ = svr.dispatch(context.Background(), slog.Default(), "default-token", GotifyNotification{})
+	if code != 500 {
+		t.Errorf("dispatch() with all targets failing = %d, want 500 of the default server", code)
+	}
+}
+
+func TestParseGotifyTargets(t *testing.T) {
+	t.Setenv("GOTIFY_TOKEN_BACKUP", "token")
+
+	for _, spec := range []string{"backup", "=http://gotify", "backup=", "default=http://gotify", "other=http://gotify", "backup=gotify"} {
+		if _, err := parseGotifyTargets([]string{spec}); err == nil {
+			t.Errorf("parseGotifyTargets(%q) succeeded", spec)
+		}
+	}
+	if _, err := parseGotifyTargets([]string{"backup=http://a", "backup=http://b"}); err == nil {
+		t.Error("parseGotifyTargets() accepted a name twice")
+	}
+}
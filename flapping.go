@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type flapState struct {
+	status      string
+	transitions []time.Time
+	lastSeen    time.Time
+	flapping    bool
+	/* The latest notification suppressed while flapping, sent once the alert settled */
+	latest *heldMessage
+}
+
+// flapDetector counts the changes between firing and resolved of each alert. Alerts changing
+// more than threshold times within window are flapping, their notifications are suppressed
+// until they settle
+type flapDetector struct {
+	threshold int
+	window    time.Duration
+	notify    bool
+
+	mu     sync.Mutex
+	alerts map[string]*flapState
+}
+
+func newFlapDetector(threshold int, window time.Duration, action string) (*flapDetector, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("the flapping window must be positive")
+	}
+	return &flapDetector{threshold: threshold, window: window, notify: action == "notify", alerts: map[string]*flapState{}}, nil
+}
+
+/* Drops the transitions that left the window, the caller holds the lock */
+func (f *flapDetector) prune(state *flapState, now time.Time) {
+	keep := 0
+	for keep < len(state.transitions) && now.Sub(state.transitions[keep]) > f.window {
+		keep++
+	}
+	state.transitions = state.transitions[keep:]
+}
+
+// suppresses records the status of an alert that is about to be sent and reports whether it is
+// flapping, in which case the notification is kept back. started tells whether the alert just
+// started flapping. Alerts without a fingerprint are never flapping
+func (f *flapDetector) suppresses(svr *bridge, alert Alert, token string, outbound GotifyNotification) (suppressed bool, started bool) {
+	if f == nil || alert.Fingerprint == "" {
+		return false, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	state, ok := f.alerts[alert.Fingerprint]
+	if !ok {
+		state = &flapState{status: alert.Status}
+		f.alerts[alert.Fingerprint] = state
+	}
+	state.lastSeen = now
+	if state.status != alert.Status {
+		state.status = alert.Status
+		state.transitions = append(state.transitions, now)
+	}
+	f.prune(state, now)
+
+	if !state.flapping && len(state.transitions) > f.threshold {
+		state.flapping, started = true, true
+	}
+	if state.flapping {
+		state.latest = &heldMessage{svr: svr, alert: alert, token: token, outbound: outbound}
+	}
+	return state.flapping, started
+}
+
+// message is sent with --flap_action=notify when an alert started flapping
+func (f *flapDetector) message(alert Alert, outbound GotifyNotification) GotifyNotification {
+	return GotifyNotification{
+		Title:    fmt.Sprintf("%s is flapping", alert.Labels["alertname"]),
+		Message:  fmt.Sprintf("%s\n\nThe alert changed between firing and resolved more than %d times within %s. Its notifications are suppressed until it settles.", outbound.Title, f.threshold, f.window),
+		Priority: outbound.Priority,
+		Extras:   outbound.Extras,
+	}
+}
+
+// settled returns the latest notification of every alert that stopped flapping and forgets the
+// alerts that didn't change for long
+func (f *flapDetector) settled() []heldMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	held := []heldMessage{}
+	for fingerprint, state := range f.alerts {
+		f.prune(state, now)
+		if state.flapping && len(state.transitions) <= f.threshold {
+			state.flapping = false
+			if state.latest != nil {
+				held = append(held, *state.latest)
+				state.latest = nil
+			}
+		}
+		/* Firing alerts are repeated by Alertmanager, so they are only forgotten when gone for long */
+		if !state.flapping && len(state.transitions) == 0 && (state.status == "resolved" || now.Sub(state.lastSeen) > escalationForgetAfter) {
+			delete(f.alerts, fingerprint)
+		}
+	}
+	return held
+}
+
+// run sends the latest notification of alerts once they stopped flapping, until the bridge exits
+func (f *flapDetector) run(svr *bridge) {
+	interval := f.window / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	slog.Info("Detecting flapping alerts", "threshold", f.threshold, "window", f.window)
+
+	for range time.Tick(interval) {
+		if svr.pause.active() {
+			continue
+		}
+		if held := f.settled(); len(held) > 0 {
+			slog.Info("Alerts stopped flapping - sending their latest notification", "count", len(held))
+			sendHeld(held)
+		}
+	}
+}
+
+/* A flapping message that could not be sent is not retried, the alert is kept back either way */
+func (svr *bridge) sendFlapping(ctx context.Context, logger *slog.Logger, token string, outbound GotifyNotification) {
+	if *svr.dryRun {
+		svr.dryRunResult(logger, "Flapping message", outbound)
+		return
+	}
+	statusCode, status, _, err := svr.dispatch(ctx, logger, token, outbound)
+	if err != nil || statusCode != 200 {
+		logger.Warn("Unable to send flapping message to gotify", "status", status, "error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlapThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		threshold  int
+		statuses   []string
+		suppressed []bool
+		started    int
+	}{
+		{
+			name:       "steady firing",
+			threshold:  1,
+			statuses:   []string{"firing", "firing", "firing"},
+			suppressed: []bool{false, false, false},
+		},
+		{
+			name:       "at the threshold",
+			threshold:  2,
+			statuses:   []string{"firing", "resolved", "firing"},
+			suppressed: []bool{false, false, false},
+		},
+		{
+			name:       "past the threshold",
+			threshold:  2,
+			statuses:   []string{"firing", "resolved", "firing", "resolved", "firing"},
+			suppressed: []bool{false, false, false, true, true},
+			started:    1,
+		},
+		{
+			name:       "repeated status while flapping",
+			threshold:  1,
+			statuses:   []string{"resolved", "firing", "resolved", "resolved"},
+			suppressed: []bool{false, false, true, true},
+			started:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, _ := newFlapDetector(tt.threshold, time.Hour, "suppress")
+			started := 0
+			for i, status := range tt.statuses {
+				suppressed, start := f.suppresses(nil, Alert{Fingerprint: "a1", Status: status}, "token", GotifyNotification{Title: status})
+				if suppressed != tt.suppressed[i] {
+					t.Errorf("notification %d (%s) suppressed = %v, want %v", i, status, suppressed, tt.suppressed[i])
+				}
+				if start {
+					started++
+				}
+			}
+			if started != tt.started {
+				t.Errorf("started flapping %d times, want %d", started, tt.started)
+			}
+		})
+	}
+}
+
+func TestFlapSettles(t *testing.T) {
+	f, _ := newFlapDetector(1, 50*time.Millisecond, "notify")
+	for _, status := range []string{"firing", "resolved", "firing"} {
+		f.suppresses(nil, Alert{Fingerprint: "a1", Status: status}, "token", GotifyNotification{Title: status})
+	}
+	f.suppresses(nil, Alert{Fingerprint: "other", Status: "firing"}, "token", GotifyNotification{})
+	if held := f.settled(); len(held) != 0 {
+		t.Fatalf("settled() = %+v while the alert is flapping", held)
+	}
+
+	/* Once the transitions left the window the latest notification is sent */
+	time.Sleep(60 * time.Millisecond)
+	held := f.settled()
+	if len(held) != 1 || held[0].alert.Fingerprint != "a1" || held[0].outbound.Title != "firing" || held[0].token != "token" {
+		t.Fatalf("settled() after the window = %+v, want the latest firing notification", held)
+	}
+	if held := f.settled(); len(held) != 0 {
+		t.Errorf("settled notification returned again: %+v", held)
+	}
+
+	/* Stable again, the next change is sent right away */
+	if suppressed, _ := f.suppresses(nil, Alert{Fingerprint: "a1", Status: "resolved"}, "token", GotifyNotification{}); suppressed {
+		t.Error("notification of a settled alert suppressed")
+	}
+	f.settled()
+	f.mu.Lock()
+	_, remembered := f.alerts["a1"]
+	f.mu.Unlock()
+	if !remembered {
+		t.Error("alert forgotten while its last change is still in the window")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	f.settled()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, remembered := f.alerts["a1"]; remembered {
+		t.Error("resolved alert still remembered after it settled")
+	}
+	if _, remembered := f.alerts["other"]; !remembered {
+		t.Error("firing alert forgotten before escalationForgetAfter")
+	}
+}
+
+func TestFlapWindow(t *testing.T) {
+	if _, err := newFlapDetector(3, 0, "suppress"); err == nil {
+		t.Error("newFlapDetector() accepted an empty window")
+	}
+
+	var disabled *flapDetector
+	if suppressed, _ := disabled.suppresses(nil, Alert{Fingerprint: "a1", Status: "firing"}, "", GotifyNotification{}); suppressed {
+		t.Error("notification suppressed without flapping detection")
+	}
+
+	f, _ := newFlapDetector(0, time.Hour, "suppress")
+	if suppressed, _ := f.suppresses(nil, Alert{Status: "firing"}, "", GotifyNotification{}); suppressed {
+		t.Error("alert without fingerprint suppressed")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookForwarder relays the body of every webhook call to further URLs, e.g. a second bridge
+// or an archive. Forwarding happens in the background and never affects the answer to the
+// webhook call
+type webhookForwarder struct {
+	urls   []*url.URL
+	client *http.Client
+}
+
+func newWebhookForwarder(rawURLs []string, timeout time.Duration) (*webhookForwarder, error) {
+	f := &webhookForwarder{client: &http.Client{Timeout: timeout}}
+	for _, rawURL := range rawURLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("forward URL %s must be http or https", u.Redacted())
+		}
+		f.urls = append(f.urls, u)
+	}
+	return f, nil
+}
+
+// forward posts the body to every URL at the same time
+func (f *webhookForwarder) forward(body []byte, contentType string) {
+	if f == nil {
+		return
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	for _, u := range f.urls {
+		go f.post(u, body, contentType)
+	}
+}
+
+func (f *webhookForwarder) post(u *url.URL, body []byte, contentType string) {
+	/* The URL may hold credentials, so only its redacted form shows up in logs and metrics */
+	name := u.Redacted()
+	logger := slog.With("forward_url", name)
+
+	resp, err := f.client.Post(u.String(), contentType, bytes.NewReader(body))
+	if err != nil {
+		logger.Warn("Unable to forward webhook", "error", err)
+		metrics.IncForward(name, "failed")
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logger.Warn("Forward URL rejected webhook", "status", resp.Status)
+		metrics.IncForward(name, "failed")
+		return
+	}
+	logger.Debug("Forwarded webhook", "status", resp.Status)
+	metrics.IncForward(name, "succeeded")
+}
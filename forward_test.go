@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func forwardCounts(name string) map[string]int {
+	_, _, _, forwards := metrics.snapshot()
+	return forwards[name]
+}
+
+func TestForwardUpstreams(t *testing.T) {
+	received := make(chan string, 1)
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r.Header.Get("Content-Type") + " " + string(body)
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		result string
+	}{
+		{"accepted", ok.URL, "succeeded"},
+		{"server error", failing.URL, "failed"},
+		{"timeout", slow.URL, "failed"},
+		{"connection refused", closed.URL, "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newWebhookForwarder([]string{tt.url + "/webhook"}, 50*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u := f.urls[0]
+			f.post(u, []byte(`{"status":"firing"}`), "application/json")
+
+			counts := forwardCounts(u.Redacted())
+			other := map[string]string{"succeeded": "failed", "failed": "succeeded"}[tt.result]
+			if counts[tt.result] != 1 || counts[other] != 0 {
+				t.Errorf("forward counters = %v, want one %s", counts, tt.result)
+			}
+		})
+	}
+
+	if body := <-received; body != `application/json {"status":"firing"}` {
+		t.Errorf("upstream received %q", body)
+	}
+}
+
+func TestForwardRedactsURL(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+
+	u, _ := url.Parse(failing.URL)
+	u.User = url.UserPassword("archive", "s3cret")
+	f, _ := newWebhookForwarder([]string{u.String()}, time.Second)
+	f.post(f.urls[0], []byte("{}"), "application/json")
+
+	if counts := forwardCounts(u.Redacted()); counts["failed"] != 1 {
+		t.Errorf("forward counters of the redacted URL = %v", counts)
+	}
+	if counts := forwardCounts(u.String()); counts != nil {
+		t.Error("forward counters exported with the password")
+	}
+}
+
+func TestForwardRejectsScheme(t *testing.T) {
+	if _, err := newWebhookForwarder([]string{"ftp://archive/webhook"}, time.Second); err == nil {
+		t.Error("newWebhookForwarder() accepted an ftp URL")
+	}
+	var disabled *webhookForwarder
+	disabled.forward([]byte("{}"), "")
+}
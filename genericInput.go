@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	ut "text/template"
+	"time"
+)
+
+// genericConfig declares how an alert is extracted from an arbitrary JSON document. Every
+// setting is a Go template executed with the decoded document
+type genericConfig struct {
+	Title       string            `yaml:"title"`
+	Message     string            `yaml:"message"`
+	Priority    string            `yaml:"priority"`
+	Status      string            `yaml:"status"`
+	Fingerprint string            `yaml:"fingerprint"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// genericInput holds the compiled templates of a genericConfig, one named template per setting
+type genericInput struct {
+	tmpl        *ut.Template
+	labels      []string
+	annotations []string
+}
+
+func newGenericInput(cfg genericConfig) (*genericInput, error) {
+	if cfg.Title == "" || cfg.Message == "" {
+		return nil, fmt.Errorf("the title and message templates are required")
+	}
+
+	g := &genericInput{tmpl: ut.New("generic").Funcs(fxns).Funcs(ut.FuncMap{"jsonPath": jsonPath})}
+	parts := map[string]string{
+		"title":       cfg.Title,
+		"message":     cfg.Message,
+		"priority":    cfg.Priority,
+		"status":      cfg.Status,
+		"fingerprint": cfg.Fingerprint,
+	}
+	for name, text := range cfg.Labels {
+		parts["label:"+name] = text
+		g.labels = append(g.labels, name)
+	}
+	for name, text := range cfg.Annotations {
+		parts["annotation:"+name] = text
+		g.annotations = append(g.annotations, name)
+	}
+	sort.Strings(g.labels)
+	sort.Strings(g.annotations)
+
+	for name, text := range parts {
+		if _, err := g.tmpl.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", name, err)
+		}
+	}
+	return g, nil
+}
+
+func (g *genericInput) execute(name string, doc interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := g.tmpl.ExecuteTemplate(buf, name, doc); err != nil {
+		return "", fmt.Errorf("error in %s template: %w", name, err)
+	}
+	/* Fields missing from the document are empty rather than <no value>, as in Prometheus */
+	return strings.TrimSpace(strings.ReplaceAll(buf.String(), "<no value>", "")), nil
+}
+
+// parseGeneric extracts an alert from any JSON document with the templates of the generic
+// setting of the endpoint
+func parseGeneric(svr *bridge, body []byte) (Notification, error) {
+	if svr.genericInput == nil {
+		return Notification{}, fmt.Errorf("the generic input format needs the generic setting of an endpoint in --config_file")
+	}
+	g := svr.genericInput
+
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return Notification{}, fmt.Errorf("invalid JSON document: %w", err)
+	}
+
+	alert, err := svr.genericAlert(g, doc, "Generic")
+	if err != nil {
+		return Notification{}, err
+	}
+	return singleAlert("generic", "generic:"+alert.Labels["alertname"], alert), nil
+}
+
+// genericAlert executes the templates of a generic input with a document. Alerts without an
+// alertname label are named defaultName
+func (svr *bridge) genericAlert(g *genericInput, doc interface{}, defaultName string) (Alert, error) {
+	values := map[string]string{}
+	for _, name := range []string{"title", "message", "priority", "status", "fingerprint"} {
+		value, err := g.execute(name, doc)
+		if err != nil {
+			return Alert{}, err
+		}
+		values[name] = value
+	}
+
+	alert := Alert{
+		Status:      "firing",
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+		Fingerprint: values["fingerprint"],
+		StartsAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	switch values["status"] {
+	case "", "firing":
+	case "resolved":
+		alert.Status = "resolved"
+		alert.EndsAt = alert.StartsAt
+		alert.StartsAt = ""
+	default:
+		return Alert{}, fmt.Errorf("the status template rendered '%s' - expected firing or resolved", values["status"])
+	}
+
+	for _, name := range g.labels {
+		value, err := g.execute("label:"+name, doc)
+		if err != nil {
+			return Alert{}, err
+		}
+		if value != "" {
+			alert.Labels[name] = value
+		}
+	}
+	if alert.Labels["alertname"] == "" {
+		alert.Labels["alertname"] = defaultName
+	}
+	for _, name := range g.annotations {
+		value, err := g.execute("annotation:"+name, doc)
+		if err != nil {
+			return Alert{}, err
+		}
+		if value != "" {
+			alert.Annotations[name] = value
+		}
+	}
+
+	alert.Annotations[*svr.titleAnnotation] = values["title"]
+	alert.Annotations[*svr.messageAnnotation] = values["message"]
+	if values["priority"] != "" && *svr.priorityAnnotation != "" {
+		if _, err := strconv.Atoi(values["priority"]); err != nil {
+			return Alert{}, fmt.Errorf("the priority template rendered '%s', which is no number", values["priority"])
+		}
+		alert.Annotations[*svr.priorityAnnotation] = values["priority"]
+	}
+
+	return alert, nil
+}
+
+var jsonPathStep = regexp.MustCompile(`^(?:\.([^.\[]+)|\[(\d+)\]|\['([^']*)'\])`)
+
+// jsonPath looks up a value in a decoded JSON document by a path such as
+// $.alerts[0].labels['host-name']. It returns nil when the path doesn't exist
+func jsonPath(path string, doc interface{}) (interface{}, error) {
+	rest := strings.TrimPrefix(path, "$")
+	current := doc
+	for rest != "" {
+		step := jsonPathStep.FindStringSubmatch(rest)
+		if step == nil {
+			return nil, fmt.Errorf("invalid JSON path '%s' at '%s'", path, rest)
+		}
+		rest = rest[len(step[0]):]
+
+		if step[2] != "" {
+			list, ok := current.([]interface{})
+			idx, _ := strconv.Atoi(step[2])
+			if !ok || idx >= len(list) {
+				return nil, nil
+			}
+			current = list[idx]
+			continue
+		}
+
+		key := step[1] + step[3]
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		current = object[key]
+	}
+	return current, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decodeTestDocument(t *testing.T, doc string) interface{} {
+	t.Helper()
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(doc)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestJSONPath(t *testing.T) {
+	doc := decodeTestDocument(t, `{"alerts":[{"labels":{"host-name":"web1","job":"node"}},{"value":3}],"status":"firing","dotted.key":"x"}`)
+
+	tests := []struct {
+		path    string
+		want    interface{}
+		wantErr bool
+	}{
+		{path: "$.status", want: "firing"},
+		{path: ".status", want: "firing"},
+		{path: "$.alerts[0].labels['host-name']", want: "web1"},
+		{path: "$.alerts[0].labels.job", want: "node"},
+		{path: "$.alerts[1].value", want: json.Number("3")},
+		{path: "$['dotted.key']", want: "x"},
+		{path: "$.alerts[2]", want: nil},
+		{path: "$.missing.deeper", want: nil},
+		{path: "$.status[0]", want: nil},
+		{path: "$.alerts.labels", want: nil},
+		{path: "$.alerts[x]", wantErr: true},
+		{path: "$..status", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := jsonPath(tt.path, doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("jsonPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonPath(%q) = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	whole, err := jsonPath("$", doc)
+	if err != nil || !reflect.DeepEqual(whole, doc) {
+		t.Errorf("jsonPath(\"$\") = %v, %v, want the whole document", whole, err)
+	}
+}
+
+func TestGenericAlert(t *testing.T) {
+	title, message, priority := "title", "message", "priority"
+	svr := &bridge{titleAnnotation: &title, messageAnnotation: &message, priorityAnnotation: &priority}
+
+	cfg := genericConfig{
+		Title:       `{{ .check }} is {{ .state }}`,
+		Message:     `{{ jsonPath "$.details.output" . }}`,
+		Priority:    `{{ .priority }}`,
+		Status:      `{{ if eq .state "ok" }}resolved{{ end }}`,
+		Fingerprint: `{{ .id }}`,
+		Labels:      map[string]string{"alertname": `{{ .name }}`, "host": `{{ .host }}`},
+		Annotations: map[string]string{"runbook": `{{ .runbook }}`},
+	}
+	g, err := newGenericInput(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		doc     string
+		want    Alert
+		wantErr bool
+	}{
+		{
+			name: "firing",
+			doc:  `{"check":"disk","state":"critical","details":{"output":"95% full"},"priority":"8","id":"abc","name":"DiskFull","host":"web1","runbook":"https://wiki/disk"}`,
+			want: Alert{
+				Status:      "firing",
+				Fingerprint: "abc",
+				Labels:      map[string]string{"alertname": "DiskFull", "host": "web1"},
+				Annotations: map[string]string{"title": "disk is critical", "message": "95% full", "priority": "8", "runbook": "https://wiki/disk"},
+			},
+		},
+		{
+			name: "resolved with defaults",
+			doc:  `{"check":"disk","state":"ok","details":{"output":"fine"}}`,
+			want: Alert{
+				Status:      "resolved",
+				Labels:      map[string]string{"alertname": "Generic"},
+				Annotations: map[string]string{"title": "disk is ok", "message": "fine"},
+			},
+		},
+		{
+			name:    "priority no number",
+			doc:     `{"check":"disk","state":"critical","priority":"high"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svr.genericAlert(g, decodeTestDocument(t, tt.doc), "Generic")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("genericAlert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if tt.want.Status == "resolved" {
+				if got.StartsAt != "" || got.EndsAt == "" {
+					t.Errorf("genericAlert() StartsAt = %q, EndsAt = %q, want only EndsAt", got.StartsAt, got.EndsAt)
+				}
+			} else if got.StartsAt == "" || got.EndsAt != "" {
+				t.Errorf("genericAlert() StartsAt = %q, EndsAt = %q, want only StartsAt", got.StartsAt, got.EndsAt)
+			}
+			got.StartsAt, got.EndsAt = "", ""
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("genericAlert() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenericAlertStatus(t *testing.T) {
+	title, message, priority := "title", "message", ""
+	svr := &bridge{titleAnnotation: &title, messageAnnotation: &message, priorityAnnotation: &priority}
+
+	g, err := newGenericInput(genericConfig{Title: "t", Message: "m", Status: "{{ .status }}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		status  string
+		want    string
+		wantErr bool
+	}{
+		{status: "", want: "firing"},
+		{status: "firing", want: "firing"},
+		{status: "resolved", want: "resolved"},
+		{status: "ok", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			got, err := svr.genericAlert(g, map[string]interface{}{"status": tt.status}, "Generic")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("genericAlert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Status != tt.want {
+				t.Errorf("genericAlert() status = %q, want %q", got.Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// healthMonitor probes the /health endpoint of gotify in the background, so scrapes of the
+// metrics and the admin API answer from the last result instead of waiting for gotify
+type healthMonitor struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	up      bool
+	status  map[string]string
+	checked time.Time
+}
+
+func newHealthMonitor(interval time.Duration) *healthMonitor {
+	return &healthMonitor{interval: interval}
+}
+
+// run probes gotify right away and then every interval, until the bridge exits
+func (h *healthMonitor) run(svr *bridge) {
+	h.check(svr)
+	for range time.Tick(h.interval) {
+		h.check(svr)
+	}
+}
+
+func (h *healthMonitor) check(svr *bridge) {
+	up, status := svr.gotifyHealth()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.up, h.status, h.checked = up, status, time.Now()
+}
+
+// last returns the result of the last probe. checked is zero until gotify was first probed
+func (h *healthMonitor) last() (up bool, status map[string]string, checked time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.up, h.status, h.checked
+}
+
+// cachedGotifyHealth returns the last health of gotify probed in the background, probing it
+// now when the health isn't monitored or wasn't probed yet
+func (svr *bridge) cachedGotifyHealth() (bool, map[string]string) {
+	if svr.health != nil {
+		if up, status, checked := svr.health.last(); !checked.IsZero() {
+			return up, status
+		}
+	}
+	return svr.gotifyHealth()
+}
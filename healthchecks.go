@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// healthchecksPayload is the request body of the webhook integration of Healthchecks.io as
+// documented in the README, built from the placeholders Healthchecks.io fills in
+type healthchecksPayload struct {
+	Code        string `json:"code"`
+	Status      string `json:"status"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	Tags        string `json:"tags"`
+	Now         string `json:"now"`
+	LastPing    string `json:"last_ping"`
+	URL         string `json:"url"`
+}
+
+// parseHealthchecks converts a notification of Healthchecks.io into an alert named after the
+// check. The alert fires when the check goes down and resolves when it is up again
+func parseHealthchecks(svr *bridge, body []byte) (Notification, error) {
+	var payload healthchecksPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Notification{}, fmt.Errorf("invalid Healthchecks.io notification: %w", err)
+	}
+	if payload.Code == "" || payload.Name == "" {
+		return Notification{}, fmt.Errorf("invalid Healthchecks.io notification: code and name are required")
+	}
+
+	alert := Alert{
+		Labels:      map[string]string{"alertname": payload.Name, "check": payload.Code},
+		Annotations: map[string]string{},
+		Fingerprint: "healthchecks-" + payload.Code,
+	}
+	priority := 0
+	switch strings.ToLower(payload.Status) {
+	case "down":
+		alert.Status, priority = "firing", 8
+		alert.Labels[*svr.severityLabel] = "critical"
+	case "up":
+		alert.Status, priority = "resolved", 2
+		alert.Labels[*svr.severityLabel] = "info"
+	default:
+		return Notification{}, fmt.Errorf("invalid Healthchecks.io notification: unknown status '%s' - expected up or down", payload.Status)
+	}
+	if payload.Slug != "" {
+		alert.Labels["slug"] = payload.Slug
+	}
+	if tags := strings.Join(strings.Fields(payload.Tags), ","); tags != "" {
+		alert.Labels["tags"] = tags
+	}
+	if strings.HasPrefix(payload.URL, "http") {
+		alert.GeneratorURL = payload.URL
+	}
+
+	message := fmt.Sprintf("%s is %s", payload.Name, strings.ToLower(payload.Status))
+	if payload.Description != "" {
+		message += "\n\n" + payload.Description
+	}
+	if lastPing := strings.TrimSpace(payload.LastPing); lastPing != "" {
+		message += "\n\nLast ping: " + lastPing
+	}
+	alert.Annotations[*svr.titleAnnotation] = fmt.Sprintf("%s is %s", payload.Name, strings.ToUpper(payload.Status))
+	alert.Annotations[*svr.messageAnnotation] = message
+	svr.inputPriority(alert, priority)
+
+	if now, err := time.Parse(time.RFC3339, payload.Now); err == nil {
+		if alert.Status == "firing" {
+			alert.StartsAt = now.Format(time.RFC3339)
+		} else {
+			alert.EndsAt = now.Format(time.RFC3339)
+		}
+	}
+
+	return singleAlert("healthchecks", "healthchecks:"+payload.Code, alert), nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestParseHealthchecks(t *testing.T) {
+	svr := newInputTestBridge()
+
+	down, err := parseHealthchecks(svr, []byte(`{"code":"5f1c","status":"down","name":"Backup","slug":"backup","description":"Nightly backup","tags":"prod  db",
+		"now":"2024-03-01T02:00:00+00:00","last_ping":"2024-02-29T02:00:00+00:00","url":"https://healthchecks.io/checks/5f1c/details/"}`))
+	if err != nil {
+		t.Fatalf("parseHealthchecks() of a down check error = %v", err)
+	}
+	checkInputAlert(t, down, Alert{
+		Status:       "firing",
+		Fingerprint:  "healthchecks-5f1c",
+		Labels:       map[string]string{"alertname": "Backup", "check": "5f1c", "severity": "critical", "slug": "backup", "tags": "prod,db"},
+		Annotations:  map[string]string{"title": "Backup is DOWN", "description": "Backup is down\n\nNightly backup\n\nLast ping: 2024-02-29T02:00:00+00:00", "priority": "8"},
+		GeneratorURL: "https://healthchecks.io/checks/5f1c/details/",
+		StartsAt:     "2024-03-01T02:00:00Z",
+	})
+
+	/* The same check resolves the alert, the unexpanded $URL placeholder is dropped */
+	up, err := parseHealthchecks(svr, []byte(`{"code":"5f1c","status":"UP","name":"Backup","now":"2024-03-01T02:05:00Z","url":"$URL"}`))
+	if err != nil {
+		t.Fatalf("parseHealthchecks() of an up check error = %v", err)
+	}
+	checkInputAlert(t, up, Alert{
+		Status:      "resolved",
+		Fingerprint: "healthchecks-5f1c",
+		Labels:      map[string]string{"alertname": "Backup", "check": "5f1c", "severity": "info"},
+		Annotations: map[string]string{"title": "Backup is UP", "description": "Backup is up", "priority": "2"},
+		EndsAt:      "2024-03-01T02:05:00Z",
+	})
+
+	for _, body := range []string{
+		`{"code":"5f1c","status":"grace","name":"Backup"}`,
+		`{"status":"down","name":"Backup"}`,
+		`{"code":"5f1c","status":"down"}`,
+		`down`,
+	} {
+		if _, err := parseHealthchecks(svr, []byte(body)); err == nil {
+			t.Errorf("parseHealthchecks(%s) accepted an invalid notification", body)
+		}
+	}
+}
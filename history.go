@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// historyEntry is the outcome of processing a single alert, as shown by the admin API
+type historyEntry struct {
+	Time         time.Time         `json:"time"`
+	Fingerprint  string            `json:"fingerprint,omitempty"`
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Title        string            `json:"title"`
+	Message      string            `json:"message"`
+	Priority     int               `json:"priority"`
+	Outcome      string            `json:"outcome"`
+	GotifyStatus int               `json:"gotify_status,omitempty"`
+	MessageID    int               `json:"message_id,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// alertHistory keeps the last processed alerts, and separately the last ones that failed so
+// failures are not pushed out by a burst of successful alerts. With a database, every alert is
+// persisted there as well, and with an audit log, written to it. Clients of /api/v1/events
+// are told about every alert as it is recorded
+type alertHistory struct {
+	size   int
+	db     *historyDatabase
+	audit  *auditLog
+	events *eventBroker
+
+	mu     sync.Mutex
+	recent []historyEntry
+	failed []historyEntry
+}
+
+func newAlertHistory(size int, db *historyDatabase, audit *auditLog) *alertHistory {
+	return &alertHistory{size: size, db: db, audit: audit}
+}
+
+// add records the outcome of an alert. statusCode is the status gotify answered with, 0 when
+// the alert was not sent to gotify. Nothing is recorded when the history is disabled
+func (h *alertHistory) add(alert Alert, outbound GotifyNotification, outcome string, statusCode int, messageID int, err error) {
+	if h == nil || (h.size <= 0 && h.db == nil && h.audit == nil && h.events == nil) {
+		return
+	}
+
+	entry := historyEntry{
+		Time:         time.Now(),
+		Fingerprint:  alert.Fingerprint,
+		Status:       alert.Status,
+		Labels:       alert.Labels,
+		Title:        outbound.Title,
+		Message:      outbound.Message,
+		Priority:     outbound.Priority,
+		Outcome:      outcome,
+		GotifyStatus: statusCode,
+		MessageID:    messageID,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if h.db != nil {
+		if err := h.db.insert(entry); err != nil {
+			slog.Warn("Unable to write alert to history database", "fingerprint", alert.Fingerprint, "error", err)
+		}
+	}
+	if h.audit != nil {
+		record := auditRecord{
+			Time:         entry.Time,
+			Outcome:      outcome,
+			Fingerprint:  alert.Fingerprint,
+			Status:       alert.Status,
+			StartsAt:     alert.StartsAt,
+			EndsAt:       alert.EndsAt,
+			Labels:       alert.Labels,
+			Annotations:  alert.Annotations,
+			Title:        outbound.Title,
+			Message:      outbound.Message,
+			Priority:     outbound.Priority,
+			Extras:       outbound.Extras,
+			GotifyStatus: statusCode,
+			MessageID:    messageID,
+			Error:        entry.Error,
+		}
+		if err := h.audit.write(record); err != nil {
+			slog.Warn("Unable to write alert to audit log", "fingerprint", alert.Fingerprint, "error", err)
+		}
+	}
+	h.events.publish(entry)
+	if h.size <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recent = appendLimited(h.recent, entry, h.size)
+	if outcome == "failed" || outcome == "render_failed" {
+		h.failed = appendLimited(h.failed, entry, h.size)
+	}
+}
+
+func appendLimited(entries []historyEntry, entry historyEntry, size int) []historyEntry {
+	entries = append(entries, entry)
+	if len(entries) > size {
+		entries = entries[len(entries)-size:]
+	}
+	return entries
+}
+
+/* Returns a copy of the entries, newest first */
+func (h *alertHistory) list(failed bool) []historyEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := h.recent
+	if failed {
+		entries = h.failed
+	}
+	result := make([]historyEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		result = append(result, entries[i])
+	}
+	return result
+}
+
+type queueStatus struct {
+	Async          bool `json:"async"`
+	Depth          int  `json:"depth"`
+	Capacity       int  `json:"capacity"`
+	Paused         bool `json:"paused"`
+	HeldPaused     int  `json:"held_paused"`
+	HeldQuietHours int  `json:"held_quiet_hours"`
+	ReplayPending  int  `json:"replay_pending"`
+}
+
+// handleRecent returns the last processed alerts
+func (svr *bridge) handleRecent(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, svr.history.list(false))
+}
+
+// handleFailed returns the last alerts that could not be rendered or sent to gotify
+func (svr *bridge) handleFailed(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, svr.history.list(true))
+}
+
+// handleQueue returns the webhook calls waiting in --async mode, the notifications held back by
+// a pause or quiet hours and those waiting to be replayed
+func (svr *bridge) handleQueue(w http.ResponseWriter, r *http.Request) {
+	pause := svr.pause.status()
+	status := queueStatus{
+		Async:         svr.queue != nil,
+		Depth:         len(svr.queue),
+		Capacity:      cap(svr.queue),
+		Paused:        pause.Paused,
+		HeldPaused:    pause.Held,
+		ReplayPending: svr.replay.count(),
+	}
+	if svr.quietHours != nil {
+		status.HeldQuietHours = svr.quietHours.held.count()
+	}
+	writeJSON(w, status)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
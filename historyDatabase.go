@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	/* Pure Go, so release builds keep working with CGO_ENABLED=0 */
+	_ "modernc.org/sqlite"
+)
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS alerts (
+	time          INTEGER NOT NULL,
+	fingerprint   TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	alertname     TEXT NOT NULL,
+	labels        TEXT NOT NULL,
+	title         TEXT NOT NULL,
+	message       TEXT NOT NULL,
+	priority      INTEGER NOT NULL,
+	outcome       TEXT NOT NULL,
+	gotify_status INTEGER NOT NULL,
+	message_id    INTEGER NOT NULL,
+	error         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS alerts_time ON alerts (time);
+CREATE INDEX IF NOT EXISTS alerts_alertname ON alerts (alertname, time);
+CREATE INDEX IF NOT EXISTS alerts_fingerprint ON alerts (fingerprint, time);
+`
+
+// historyDatabase persists the history of processed alerts in SQLite so it survives restarts
+// and reaches back further than the in-memory history. Entries older than the retention are
+// pruned regularly
+type historyDatabase struct {
+	db        *sql.DB
+	retention time.Duration
+}
+
+func openHistoryDatabase(path string, retention time.Duration) (*historyDatabase, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open history database %s: %w", path, err)
+	}
+
+	/* SQLite allows a single writer only - serialize access instead of failing with SQLITE_BUSY */
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to initialize history database %s: %w", path, err)
+	}
+	return &historyDatabase{db: db, retention: retention}, nil
+}
+
+func (d *historyDatabase) insert(entry historyEntry) error {
+	labels, err := json.Marshal(entry.Labels)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(`INSERT INTO alerts (time, fingerprint, status, alertname, labels, title, message, priority, outcome, gotify_status, message_id, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Time.UnixMilli(), entry.Fingerprint, entry.Status, entry.Labels["alertname"], string(labels),
+		entry.Title, entry.Message, entry.Priority, entry.Outcome, entry.GotifyStatus, entry.MessageID, entry.Error)
+	return err
+}
+
+// historyQuery selects entries of the history database. Empty fields match all entries
+type historyQuery struct {
+	since       time.Time
+	until       time.Time
+	alertname   string
+	fingerprint string
+	status      string
+	outcome     string
+	limit       int
+}
+
+/* Returns the matching entries, newest first */
+func (d *historyDatabase) query(ctx context.Context, q historyQuery) ([]historyEntry, error) {
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if !q.since.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, q.since.UnixMilli())
+	}
+	if !q.until.IsZero() {
+		conditions = append(conditions, "time <= ?")
+		args = append(args, q.until.UnixMilli())
+	}
+	for column, value := range map[string]string{"alertname": q.alertname, "fingerprint": q.fingerprint, "status": q.status, "outcome": q.outcome} {
+		if value != "" {
+			conditions = append(conditions, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	args = append(args, q.limit)
+
+	rows, err := d.db.QueryContext(ctx, `SELECT time, fingerprint, status, labels, title, message, priority, outcome, gotify_status, message_id, error
+		FROM alerts WHERE `+strings.Join(conditions, " AND ")+` ORDER BY time DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []historyEntry{}
+	for rows.Next() {
+		var entry historyEntry
+		var millis int64
+		var labels string
+		err := rows.Scan(&millis, &entry.Fingerprint, &entry.Status, &labels, &entry.Title, &entry.Message,
+			&entry.Priority, &entry.Outcome, &entry.GotifyStatus, &entry.MessageID, &entry.Error)
+		if err != nil {
+			return nil, err
+		}
+		entry.Time = time.UnixMilli(millis).UTC()
+		if err := json.Unmarshal([]byte(labels), &entry.Labels); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+/* Deletes the entries older than the retention and returns how many were deleted */
+func (d *historyDatabase) prune() (int64, error) {
+	result, err := d.db.Exec("DELETE FROM alerts WHERE time < ?", time.Now().Add(-d.retention).UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// run prunes the entries older than the retention every hour until the bridge exits. Nothing
+// is pruned when the retention is 0
+func (d *historyDatabase) run() {
+	if d.retention <= 0 {
+		return
+	}
+
+	for ; ; time.Sleep(time.Hour) {
+		deleted, err := d.prune()
+		if err != nil {
+			slog.Warn("Unable to prune history database", "error", err)
+			continue
+		}
+		slog.Debug("Pruned history database", "deleted", deleted, "retention", d.retention)
+	}
+}
+
+// handleHistory returns the entries of the history database matching the query parameters
+// since and until (RFC3339 or a duration before now like 12h), alertname, fingerprint, status,
+// outcome and limit
+func (svr *bridge) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if svr.history == nil || svr.history.db == nil {
+		http.Error(w, "The history database is disabled - set --history_database to enable it", http.StatusNotFound)
+		return
+	}
+
+	params := r.URL.Query()
+	q := historyQuery{
+		alertname:   params.Get("alertname"),
+		fingerprint: params.Get("fingerprint"),
+		status:      params.Get("status"),
+		outcome:     params.Get("outcome"),
+		limit:       100,
+	}
+
+	var err error
+	if q.since, err = parseHistoryTime(params.Get("since")); err != nil {
+		http.Error(w, "Invalid since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.until, err = parseHistoryTime(params.Get("until")); err != nil {
+		http.Error(w, "Invalid until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if val := params.Get("limit"); val != "" {
+		if q.limit, err = strconv.Atoi(val); err != nil || q.limit <= 0 {
+			http.Error(w, "Invalid limit: "+val, http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := svr.history.db.query(r.Context(), q)
+	if err != nil {
+		slog.Error("Unable to query history database", "error", err)
+		http.Error(w, "Unable to query history database", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, entries)
+}
+
+/* Accepts RFC3339 times and durations before now. Empty values give the zero time */
+func parseHistoryTime(val string) (time.Time, error) {
+	if val == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(val); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
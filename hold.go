@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// heldMessage is a notification held back, e.g. during quiet hours, to be sent later. The
+// notification of --group_alerts covers all alerts of the group instead of a single one.
+// attempts counts how often a failed notification was replayed
+type heldMessage struct {
+	svr      *bridge
+	alert    Alert
+	group    []groupedAlert
+	token    string
+	outbound GotifyNotification
+	attempts int
+}
+
+/* Counts and records the outcome for every alert of the notification */
+func (h heldMessage) record(outcome string, statusCode int, messageID int, err error) {
+	metric := "alerts_processed"
+	if outcome == "failed" {
+		metric = "alerts_failed"
+	}
+	h.count(metric)
+
+	if h.group == nil {
+		h.svr.history.add(h.alert, h.outbound, outcome, statusCode, messageID, err)
+		h.svr.dispatchHook.fire(h.alert, h.outbound, outcome, statusCode, messageID, err)
+		return
+	}
+	for _, g := range h.group {
+		h.svr.history.add(g.alert, g.notification, outcome, statusCode, messageID, err)
+		h.svr.dispatchHook.fire(g.alert, g.notification, outcome, statusCode, messageID, err)
+	}
+}
+
+/* Counts every alert of a notification under the given metric */
+func (h heldMessage) count(metric string) {
+	if h.group == nil {
+		h.svr.countAlert(metric, h.alert)
+		return
+	}
+	for _, g := range h.group {
+		h.svr.countAlert(metric, g.alert)
+	}
+}
+
+type heldMessages struct {
+	size int
+
+	mu       sync.Mutex
+	messages []heldMessage
+}
+
+// hold keeps a notification back until it is released. A resolved alert whose firing
+// notification is still held cancels it instead, as there is nothing left to tell - in which
+// case false is returned
+func (h *heldMessages) hold(svr *bridge, alert Alert, token string, outbound GotifyNotification) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if alert.Status == "resolved" && alert.Fingerprint != "" {
+		for i, m := range h.messages {
+			if m.alert.Fingerprint == alert.Fingerprint && m.alert.Status == "firing" {
+				h.messages = append(h.messages[:i], h.messages[i+1:]...)
+				return false
+			}
+		}
+	}
+
+	h.add(heldMessage{svr: svr, alert: alert, token: token, outbound: outbound})
+	return true
+}
+
+/* Callers must hold the lock. The oldest notification is dropped once the queue is full */
+func (h *heldMessages) add(m heldMessage) {
+	h.messages = append(h.messages, m)
+	if h.size > 0 && len(h.messages) > h.size {
+		dropped := h.messages[0]
+		h.messages = h.messages[1:]
+		dropped.count("alerts_hold_dropped")
+		slog.Warn("Held notifications full - dropping oldest notification", "fingerprint", dropped.alert.Fingerprint, "title", dropped.outbound.Title)
+	}
+}
+
+/* Holds back a notification again, e.g. when quiet hours began before it was sent */
+func (h *heldMessages) requeue(m heldMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.add(m)
+}
+
+func (h *heldMessages) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.messages)
+}
+
+/* Returns the held notifications and forgets them */
+func (h *heldMessages) release() []heldMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	held := h.messages
+	h.messages = nil
+	return held
+}
+
+// sendHeld dispatches notifications that were held back and returns how many were sent.
+// Notifications gotify did not accept are kept for replay, and those below the priority of
+// quiet hours that began in the meantime are held back or suppressed again
+func sendHeld(held []heldMessage) int {
+	sent := 0
+	for _, h := range held {
+		logger := slog.With("fingerprint", h.alert.Fingerprint, "status", h.alert.Status)
+		if quiet := h.svr.quietHours; quiet.suppresses(h.outbound.Priority) {
+			h.count("alerts_quieted")
+			if quiet.queue {
+				logger.Info("Held alert processed", "outcome", "held for quiet hours", "priority", h.outbound.Priority)
+				quiet.held.requeue(h)
+			} else {
+				logger.Info("Held alert processed", "outcome", "suppressed for quiet hours", "priority", h.outbound.Priority)
+			}
+			continue
+		}
+		if *h.svr.dryRun {
+			h.svr.dryRunResult(logger, "Held message", h.outbound)
+			continue
+		}
+
+		statusCode, status, messageID, err := h.svr.dispatch(context.Background(), logger, h.token, h.outbound)
+		if err != nil || statusCode != 200 {
+			logger.Warn("Held alert processed", "outcome", "failed", "status", status, "error", err)
+			if err == nil {
+				err = errors.New(status)
+			}
+			h.record("failed", statusCode, 0, err)
+			h.svr.replay.keep(h)
+			continue
+		}
+		logger.Info("Held alert processed", "outcome", "dispatched", "message_id", messageID)
+		h.record("dispatched", statusCode, messageID, nil)
+		h.svr.replay.forget(h.alert.Fingerprint)
+		sent++
+	}
+	return sent
+}
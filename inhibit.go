@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* Source alerts not received again for this long are assumed resolved without a notification */
+const inhibitForgetAfter = 24 * time.Hour
+
+// inhibitRuleConfig mutes alerts matching the target matchers while an alert matching the
+// source matchers is firing, like inhibit_rules of Alertmanager. Labels listed in equal must
+// have the same value in both alerts
+type inhibitRuleConfig struct {
+	SourceMatchers []string `yaml:"source_matchers"`
+	TargetMatchers []string `yaml:"target_matchers"`
+	Equal          []string `yaml:"equal"`
+}
+
+type inhibitRule struct {
+	source matcherSet
+	target matcherSet
+	equal  []string
+}
+
+type inhibitSource struct {
+	labels   map[string]string
+	lastSeen time.Time
+}
+
+// inhibitor tracks the firing alerts matching the source matchers of any rule. It serves alerts
+// of all sources, including those that never pass through Alertmanager and its inhibition
+type inhibitor struct {
+	rules []inhibitRule
+
+	mu      sync.Mutex
+	sources map[string]*inhibitSource
+}
+
+func newInhibitor(configs []inhibitRuleConfig) (*inhibitor, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	i := &inhibitor{sources: map[string]*inhibitSource{}}
+	for _, cfg := range configs {
+		rule := inhibitRule{equal: cfg.Equal}
+		var err error
+		if rule.source, err = parseMatcherSet(strings.Join(cfg.SourceMatchers, ",")); err != nil {
+			return nil, fmt.Errorf("invalid source_matchers of inhibit rule: %w", err)
+		}
+		if rule.target, err = parseMatcherSet(strings.Join(cfg.TargetMatchers, ",")); err != nil {
+			return nil, fmt.Errorf("invalid target_matchers of inhibit rule: %w", err)
+		}
+		i.rules = append(i.rules, rule)
+	}
+	return i, nil
+}
+
+// loadInhibitor reads the inhibit rules of --config_file, exiting when they are invalid. It is
+// nil when there are none
+func loadInhibitor(path string) *inhibitor {
+	cfg, err := loadBridgeConfig(path)
+	var i *inhibitor
+	if err == nil {
+		i, err = newInhibitor(cfg.InhibitRules)
+	}
+	if err != nil {
+		slog.Error("Invalid inhibit rules in config file", "error", err)
+		os.Exit(1)
+	}
+	return i
+}
+
+// observe records the firing alerts of a notification that may inhibit others and forgets the
+// resolved ones. All alerts of a notification are observed before any of them is dispatched,
+// so the order of the alerts doesn't matter
+func (i *inhibitor) observe(alerts []Alert) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	now := time.Now()
+	for fingerprint, source := range i.sources {
+		if now.Sub(source.lastSeen) > inhibitForgetAfter {
+			delete(i.sources, fingerprint)
+		}
+	}
+
+	for _, alert := range alerts {
+		key := inhibitKey(alert)
+		if alert.Status != "firing" {
+			delete(i.sources, key)
+			continue
+		}
+		for _, rule := range i.rules {
+			if rule.source.matches(alert.Labels) {
+				i.sources[key] = &inhibitSource{labels: alert.Labels, lastSeen: now}
+				break
+			}
+		}
+	}
+}
+
+// inhibits reports whether a firing source alert mutes the alert. An alert never inhibits itself
+func (i *inhibitor) inhibits(alert Alert) bool {
+	if i == nil {
+		return false
+	}
+
+	key := inhibitKey(alert)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, rule := range i.rules {
+		if !rule.target.matches(alert.Labels) {
+			continue
+		}
+		for sourceKey, source := range i.sources {
+			if sourceKey != key && rule.source.matches(source.labels) && sameLabels(rule.equal, source.labels, alert.Labels) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* Inputs other than Alertmanager may leave out the fingerprint, it is derived from the labels then */
+func inhibitKey(alert Alert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+	return relayFingerprint(alert.Labels)
+}
+
+func sameLabels(names []string, a map[string]string, b map[string]string) bool {
+	for _, name := range names {
+		if a[name] != b[name] {
+			return false
+		}
+	}
+	return true
+}
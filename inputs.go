@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// inputParser turns the payload of a webhook call of another tool into the notification
+// Alertmanager would send, so it is rendered, filtered and dispatched the same way
+type inputParser func(svr *bridge, body []byte) (Notification, error)
+
+/* Formats besides alertmanager, selected with --input_format or format of an endpoint */
+var inputParsers = map[string]inputParser{
+	"zabbix":       parseZabbix,
+	"slack":        parseSlack,
+	"discord":      parseDiscord,
+	"uptime-kuma":  parseKuma,
+	"healthchecks": parseHealthchecks,
+	"generic":      parseGeneric,
+}
+
+func inputFormats() []string {
+	formats := []string{"alertmanager"}
+	for format := range inputParsers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats[1:])
+	return formats
+}
+
+// parseInputFormat validates --input_format, exiting when the format is unknown
+func parseInputFormat(format *string) *string {
+	if err := checkMainInputFormat(*format); err != nil {
+		slog.Error("Invalid --input_format", "error", err)
+		os.Exit(1)
+	}
+	return format
+}
+
+/* The generic format needs the templates only endpoints of --config_file can declare */
+func checkMainInputFormat(format string) error {
+	if format == "generic" {
+		return fmt.Errorf("the generic format is only available for endpoints of --config_file")
+	}
+	return checkInputFormat(format)
+}
+
+func checkInputFormat(format string) error {
+	if _, ok := inputParsers[format]; !ok && format != "alertmanager" {
+		return fmt.Errorf("unknown input format '%s' - expected one of %s", format, strings.Join(inputFormats(), ", "))
+	}
+	return nil
+}
+
+// parseNotification decodes the payload of a webhook call in the input format of the bridge
+func (svr *bridge) parseNotification(body []byte) (Notification, error) {
+	var notification Notification
+	if parse, ok := inputParsers[*svr.inputFormat]; ok {
+		return parse(svr, body)
+	}
+	err := json.Unmarshal(body, &notification)
+	return notification, err
+}
+
+// singleAlert wraps an alert converted from another tool into a notification of its own.
+// Annotations are rendered as templates, so they are turned into literals first
+func singleAlert(receiver string, groupKey string, alert Alert) Notification {
+	for name, value := range alert.Annotations {
+		alert.Annotations[name] = literalTemplate(value)
+	}
+	return Notification{
+		Version:           "4",
+		GroupKey:          groupKey,
+		Status:            alert.Status,
+		Receiver:          receiver,
+		GroupLabels:       map[string]string{},
+		CommonLabels:      alert.Labels,
+		CommonAnnotations: alert.Annotations,
+		Alerts:            []Alert{alert},
+	}
+}
+
+// inputPriority gives an alert of another tool the priority fitting its status, unless
+// --severity_priority maps the severity of the alert
+func (svr *bridge) inputPriority(alert Alert, priority int) {
+	if _, mapped := svr.severityPriorities[alert.Labels[*svr.severityLabel]]; !mapped && *svr.priorityAnnotation != "" {
+		alert.Annotations[*svr.priorityAnnotation] = strconv.Itoa(priority)
+	}
+}
+
+/* Text holding template actions is wrapped in a string constant, which renders as it is */
+func literalTemplate(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return "{{ " + strconv.Quote(s) + " }}"
+}
+
+// colorPriority classifies the color of a chat message into a severity along with the priority
+// fitting it: red for critical, yellow and orange for warning and everything else for info
+func colorPriority(red, green, blue int) (string, int) {
+	switch {
+	case red >= 0xc0 && green < 0x80:
+		return "critical", 8
+	case red >= 0xc0 && green >= 0x80 && blue < 0x80:
+		return "warning", 5
+	default:
+		return "info", 2
+	}
+}
+
+// inputHandler wraps every route accepting alerts. It counts and throttles the requests and
+// rejects those without a valid JWT of --jwt_jwks_url or HMAC signature of $WEBHOOK_HMAC_SECRET
+// before the route handles them
+func (svr *bridge) inputHandler(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := contextLogger(r.Context())
+		metrics.Inc("requests_received")
+		if svr.throttle(w, r) {
+			return
+		}
+		if svr.jwt != nil {
+			if err := svr.jwt.verify(r); err != nil {
+				log.Warn("Invalid JWT", "error", err, "remote_addr", r.RemoteAddr)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				metrics.Inc("requests_invalid")
+				return
+			}
+		}
+
+		/* The signature covers the body as sent, so it is checked before decompressing */
+		if webhookSecret != "" {
+			body := io.Reader(r.Body)
+			if *maxRequestBytes > 0 {
+				body = http.MaxBytesReader(w, r.Body, *maxRequestBytes)
+			}
+			signed, err := io.ReadAll(body)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				rejectTooLarge(log, w, r, tooLarge)
+				return
+			}
+			if err == nil {
+				err = verifySignature([]byte(webhookSecret), r.Header.Get(*signatureHeader), signed)
+			}
+			if err != nil {
+				log.Warn("Invalid webhook signature", "error", err, "remote_addr", r.RemoteAddr)
+				http.Error(w, "Invalid signature", http.StatusUnauthorized)
+				metrics.Inc("requests_invalid")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(signed))
+		}
+		handler(w, r)
+	}
+}
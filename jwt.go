@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	/* Unknown key IDs trigger a refetch of the JWKS, but not more often than this */
+	jwksMinRefetch = time.Minute
+	/* Tolerated clock skew between the bridge and the issuer */
+	jwtLeeway = 30 * time.Second
+)
+
+// jwtVerifier accepts webhook calls carrying a JWT in the Authorization header that is signed
+// by a key of the JWKS and, when configured, was issued by the issuer for the audience
+type jwtVerifier struct {
+	jwksURL string
+	refresh time.Duration
+	client  *http.Client
+	parser  *jwt.Parser
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	fetched  time.Time
+	fetching bool
+}
+
+func newJWTVerifier(jwksURL string, issuer string, audience string, refresh time.Duration) *jwtVerifier {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+
+	v := &jwtVerifier{jwksURL: jwksURL, refresh: refresh, client: &http.Client{Timeout: 10 * time.Second}, parser: jwt.NewParser(options...)}
+	/* The issuer may not be up yet, keys are fetched again on the first request then */
+	var err error
+	v.fetched = time.Now()
+	if v.keys, err = v.fetch(); err != nil {
+		slog.Warn("Unable to fetch JWKS", "url", jwksURL, "error", err)
+	}
+	return v
+}
+
+// verify checks the bearer token of a request
+func (v *jwtVerifier) verify(r *http.Request) error {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return errors.New("bearer token missing")
+	}
+	_, err := v.parser.Parse(strings.TrimSpace(header[7:]), v.key)
+	return err
+}
+
+// key looks up the key a token was signed with. A JWKS that is due is fetched in the
+// background while requests go on with the keys fetched before. An unknown key is fetched
+// right away, but only the request that needs it waits for the issuer
+func (v *jwtVerifier) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	v.mu.Lock()
+	key, ok := v.lookup(kid)
+	since := time.Since(v.fetched)
+	refetch := !v.fetching && (since > v.refresh || (!ok && since > jwksMinRefetch))
+	if refetch {
+		/* Failed attempts count as well, so an unreachable issuer isn't asked on every request */
+		v.fetching, v.fetched = true, time.Now()
+	}
+	v.mu.Unlock()
+
+	switch {
+	case refetch && !ok:
+		v.refetch()
+		v.mu.Lock()
+		key, ok = v.lookup(kid)
+		v.mu.Unlock()
+	case refetch:
+		go v.refetch()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+/* Fetches the JWKS without holding the lock and swaps in its keys */
+func (v *jwtVerifier) refetch() {
+	keys, err := v.fetch()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.fetching = false
+	if err != nil {
+		slog.Warn("Unable to fetch JWKS - using the keys fetched before", "url", v.jwksURL, "error", err)
+		return
+	}
+	v.keys = keys
+}
+
+/* Tokens without a key ID are only accepted when there is a single key, the caller holds the lock */
+func (v *jwtVerifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+/* Downloads the signing keys of the JWKS */
+func (v *jwtVerifier) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := v.client.Get(v.jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			slog.Warn("Skipping key of JWKS", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	slog.Debug("Fetched JWKS", "url", v.jwksURL, "keys", len(keys))
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[jwk.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeJWKInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+func decodeJWKInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
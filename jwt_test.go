@@ -0,0 +1,206 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/* A JWKS served by a fake issuer, the keys can be rotated and the answers held back */
+type fakeIssuer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	keys  map[string]*rsa.PrivateKey
+	hold  chan struct{}
+	calls int
+}
+
+func newFakeIssuer(t *testing.T, kids ...string) *fakeIssuer {
+	issuer := &fakeIssuer{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		issuer.keys[kid] = newJWTTestKey(t)
+	}
+	issuer.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		issuer.calls++
+		hold := issuer.hold
+		keys := []map[string]string{}
+		for kid, key := range issuer.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		issuer.mu.Unlock()
+		if hold != nil {
+			<-hold
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func newJWTTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func (issuer *fakeIssuer) rotate(t *testing.T, kid string) {
+	issuer.mu.Lock()
+	defer issuer.mu.Unlock()
+	issuer.keys = map[string]*rsa.PrivateKey{kid: newJWTTestKey(t)}
+}
+
+func (issuer *fakeIssuer) sign(t *testing.T, kid string, expires time.Time) string {
+	issuer.mu.Lock()
+	key := issuer.keys[kid]
+	issuer.mu.Unlock()
+	return signJWT(t, key, kid, expires)
+}
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, kid string, expires time.Time) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"iss": "https://idp", "aud": "bridge", "exp": expires.Unix()})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func verifyBearer(v *jwtVerifier, token string) error {
+	r := httptest.NewRequest(http.MethodPost, "/gotify_webhook", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return v.verify(r)
+}
+
+func TestJWTVerify(t *testing.T) {
+	issuer := newFakeIssuer(t, "k1")
+	v := newJWTVerifier(issuer.URL, "https://idp", "bridge", time.Hour)
+	hour := time.Now().Add(time.Hour)
+
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": "https://idp", "aud": "bridge", "exp": hour.Unix()})
+	hmac.Header["kid"] = "k1"
+	/* The public key must not be usable as HMAC secret */
+	hmacSigned, _ := hmac.SignedString(issuer.keys["k1"].N.Bytes())
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"exp": hour.Unix()}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	wrongAudience := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"iss": "https://idp", "aud": "other", "exp": hour.Unix()})
+	wrongAudience.Header["kid"] = "k1"
+	wrongAudienceSigned, _ := wrongAudience.SignedString(issuer.keys["k1"])
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid", issuer.sign(t, "k1", hour), true},
+		{"within the leeway", issuer.sign(t, "k1", time.Now().Add(-10*time.Second)), true},
+		{"expired", issuer.sign(t, "k1", time.Now().Add(-time.Minute)), false},
+		{"wrong key", signJWT(t, newJWTTestKey(t), "k1", hour), false},
+		{"HS256", hmacSigned, false},
+		{"alg none", none, false},
+		{"wrong audience", wrongAudienceSigned, false},
+		{"missing", "", false},
+		{"garbage", "not.a.token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyBearer(v, tt.token); (err == nil) != tt.valid {
+				t.Errorf("verify() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestJWTRotation(t *testing.T) {
+	issuer := newFakeIssuer(t, "k1")
+	v := newJWTVerifier(issuer.URL, "", "", time.Hour)
+	hour := time.Now().Add(time.Hour)
+
+	issuer.rotate(t, "k2")
+	rotated := issuer.sign(t, "k2", hour)
+	if err := verifyBearer(v, rotated); err == nil {
+		t.Fatal("unknown key accepted within a minute of the last fetch")
+	}
+
+	v.mu.Lock()
+	v.fetched = time.Now().Add(-2 * jwksMinRefetch)
+	v.mu.Unlock()
+	if err := verifyBearer(v, rotated); err != nil {
+		t.Fatalf("token of the rotated key rejected: %v", err)
+	}
+	issuer.mu.Lock()
+	calls := issuer.calls
+	issuer.mu.Unlock()
+	if calls != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", calls)
+	}
+	if err := verifyBearer(v, signJWT(t, newJWTTestKey(t), "k1", hour)); err == nil {
+		t.Error("token of the replaced key accepted after the rotation")
+	}
+}
+
+/* Requests are not blocked while the due JWKS is fetched */
+func TestJWTRefreshInBackground(t *testing.T) {
+	issuer := newFakeIssuer(t, "k1")
+	v := newJWTVerifier(issuer.URL, "", "", time.Hour)
+	token := issuer.sign(t, "k1", time.Now().Add(time.Hour))
+
+	hold := make(chan struct{})
+	issuer.mu.Lock()
+	issuer.hold = hold
+	issuer.mu.Unlock()
+	defer close(hold)
+	v.mu.Lock()
+	v.fetched = time.Now().Add(-2 * time.Hour)
+	v.mu.Unlock()
+
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 3; i++ {
+			if err := verifyBearer(v, token); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("verify() while refetching the JWKS: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("verify() blocked while the JWKS was fetched")
+	}
+}
+
+func TestJWTIssuerDown(t *testing.T) {
+	issuer := newFakeIssuer(t, "k1")
+	token := issuer.sign(t, "k1", time.Now().Add(time.Hour))
+	issuer.Close()
+
+	v := newJWTVerifier(issuer.URL, "", "", time.Hour)
+	if err := verifyBearer(v, token); err == nil {
+		t.Error("token accepted without keys")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+// kafkaInput consumes a topic of Kafka as member of a consumer group and dispatches every
+// record like a webhook call to path. Offsets are committed once a record was dispatched, so
+// records are sent at least once
+type kafkaInput struct {
+	config  kafka.ReaderConfig
+	path    string
+	backoff time.Duration
+}
+
+// kafkaInputFromFlags creates the Kafka input configured by the --kafka_* flags
+func kafkaInputFromFlags() (*kafkaInput, error) {
+	var tlsConfig *tls.Config
+	if *kafkaTLS {
+		var err error
+		if tlsConfig, err = clientTLSConfig(*kafkaCAFile, false); err != nil {
+			return nil, err
+		}
+	}
+	return newKafkaInput(*kafkaBrokers, *kafkaTopic, *kafkaGroup, *kafkaPath, tlsConfig, os.Getenv("KAFKA_USERNAME"), os.Getenv("KAFKA_PASSWORD"))
+}
+
+func newKafkaInput(brokers []string, topic string, group string, path string, tlsConfig *tls.Config, username string, password string) (*kafkaInput, error) {
+	if topic == "" || group == "" {
+		return nil, fmt.Errorf("the topic and consumer group are required")
+	}
+	dialer := &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true, TLS: tlsConfig}
+	if username != "" {
+		dialer.SASLMechanism = plain.Mechanism{Username: username, Password: password}
+	}
+	config := kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		GroupID:     group,
+		Dialer:      dialer,
+		StartOffset: kafka.LastOffset,
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &kafkaInput{config: config, path: path, backoff: 10 * time.Second}, nil
+}
+
+func (k *kafkaInput) run(svr *bridge) {
+	target, err := svr.pathBridge(k.path)
+	if err != nil {
+		slog.Error("Invalid --kafka_path", "error", err)
+		os.Exit(1)
+	}
+	log := slog.With("topic", k.config.Topic)
+	reader := kafka.NewReader(k.config)
+	log.Info("Consuming alerts from Kafka", "brokers", k.config.Brokers, "group", k.config.GroupID, "format", *target.inputFormat)
+
+	ctx := context.Background()
+	for {
+		record, err := reader.FetchMessage(ctx)
+		if err != nil {
+			log.Warn("Unable to fetch from Kafka", "error", err)
+			time.Sleep(k.backoff)
+			continue
+		}
+
+		/* Later records must wait, committing their offset would skip this one */
+		recordLog := log.With("partition", record.Partition, "offset", record.Offset)
+		for target.consumeMessage(recordLog, record.Value) {
+			time.Sleep(k.backoff)
+		}
+		if err = reader.CommitMessages(ctx, record); err != nil {
+			recordLog.Warn("Unable to commit the offset - the record may be dispatched again", "error", err)
+		}
+	}
+}
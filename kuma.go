@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kumaPayload is what the webhook notification of Uptime Kuma posts. Heartbeat and monitor are
+// missing from test notifications, which only carry msg
+type kumaPayload struct {
+	Heartbeat *struct {
+		MonitorID int    `json:"monitorID"`
+		Status    int    `json:"status"`
+		Time      string `json:"time"`
+		Msg       string `json:"msg"`
+	} `json:"heartbeat"`
+	Monitor *struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		URL      string `json:"url"`
+		Hostname string `json:"hostname"`
+		Tags     []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"tags"`
+	} `json:"monitor"`
+	Msg string `json:"msg"`
+}
+
+/* Status of a heartbeat in Uptime Kuma along with the alert status and severity it maps to */
+var kumaStatuses = map[int]struct {
+	name     string
+	status   string
+	severity string
+	priority int
+}{
+	0: {"down", "firing", "critical", 8},
+	1: {"up", "resolved", "info", 2},
+	2: {"pending", "firing", "warning", 5},
+	3: {"maintenance", "resolved", "info", 2},
+}
+
+// parseKuma converts a notification of Uptime Kuma into an alert named after the monitor. The
+// alert fires while the monitor is down or pending and resolves once it is up again
+func parseKuma(svr *bridge, body []byte) (Notification, error) {
+	var payload kumaPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Notification{}, fmt.Errorf("invalid Uptime Kuma notification: %w", err)
+	}
+
+	alert := Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "UptimeKuma"},
+		Annotations: map[string]string{*svr.titleAnnotation: "Uptime Kuma", *svr.messageAnnotation: payload.Msg},
+	}
+	if payload.Heartbeat == nil || payload.Monitor == nil {
+		if payload.Msg == "" {
+			return Notification{}, fmt.Errorf("invalid Uptime Kuma notification: neither heartbeat nor msg is set")
+		}
+		return singleAlert("uptime-kuma", "uptime-kuma:test", alert), nil
+	}
+
+	state, ok := kumaStatuses[payload.Heartbeat.Status]
+	if !ok {
+		return Notification{}, fmt.Errorf("invalid Uptime Kuma notification: unknown heartbeat status %d", payload.Heartbeat.Status)
+	}
+	monitor := payload.Monitor
+	id := strconv.Itoa(monitor.ID)
+	alert.Status = state.status
+	alert.Fingerprint = "uptime-kuma-" + id
+	alert.Labels = map[string]string{
+		"alertname":      monitor.Name,
+		"monitor_id":     id,
+		"monitor_type":   monitor.Type,
+		"monitor_status": state.name,
+	}
+	alert.Labels[*svr.severityLabel] = state.severity
+	for _, tag := range monitor.Tags {
+		if _, taken := alert.Labels[tag.Name]; !taken && tag.Name != "" {
+			alert.Labels[tag.Name] = tag.Value
+		}
+	}
+
+	target := monitor.URL
+	if !strings.HasPrefix(target, "http") {
+		target = monitor.Hostname
+	} else {
+		alert.GeneratorURL = target
+	}
+	if target != "" {
+		alert.Labels["instance"] = target
+	}
+
+	message := payload.Heartbeat.Msg
+	if message == "" {
+		message = fmt.Sprintf("%s is %s", monitor.Name, state.name)
+	}
+	alert.Annotations = map[string]string{
+		*svr.titleAnnotation:   fmt.Sprintf("%s is %s", monitor.Name, state.name),
+		*svr.messageAnnotation: message,
+	}
+	svr.inputPriority(alert, state.priority)
+
+	/* Heartbeats carry the time in UTC */
+	if beat, err := time.Parse("2006-01-02 15:04:05.999", payload.Heartbeat.Time); err == nil {
+		if alert.Status == "firing" {
+			alert.StartsAt = beat.Format(time.RFC3339Nano)
+		} else {
+			alert.EndsAt = beat.Format(time.RFC3339Nano)
+		}
+	}
+
+	return singleAlert("uptime-kuma", "uptime-kuma:"+id, alert), nil
+}
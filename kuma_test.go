@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestParseKuma(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    Alert
+		wantErr bool
+	}{
+		{
+			name: "down",
+			body: `{"heartbeat":{"monitorID":3,"status":0,"time":"2024-03-01 10:15:00.123","msg":"connect ECONNREFUSED"},
+				"monitor":{"id":3,"name":"Website","type":"http","url":"https://example.com","tags":[{"name":"team","value":"web"},{"name":"alertname","value":"ignored"}]},
+				"msg":"[Website] [🔴 Down] connect ECONNREFUSED"}`,
+			want: Alert{
+				Status:       "firing",
+				Fingerprint:  "uptime-kuma-3",
+				Labels:       map[string]string{"alertname": "Website", "monitor_id": "3", "monitor_type": "http", "monitor_status": "down", "severity": "critical", "team": "web", "instance": "https://example.com"},
+				Annotations:  map[string]string{"title": "Website is down", "description": "connect ECONNREFUSED", "priority": "8"},
+				GeneratorURL: "https://example.com",
+				StartsAt:     "2024-03-01T10:15:00.123Z",
+			},
+		},
+		{
+			name: "up",
+			body: `{"heartbeat":{"monitorID":4,"status":1,"time":"2024-03-01 10:20:00"},"monitor":{"id":4,"name":"Database","type":"port","url":"","hostname":"db1"}}`,
+			want: Alert{
+				Status:      "resolved",
+				Fingerprint: "uptime-kuma-4",
+				Labels:      map[string]string{"alertname": "Database", "monitor_id": "4", "monitor_type": "port", "monitor_status": "up", "severity": "info", "instance": "db1"},
+				Annotations: map[string]string{"title": "Database is up", "description": "Database is up", "priority": "2"},
+				EndsAt:      "2024-03-01T10:20:00Z",
+			},
+		},
+		{
+			name: "pending with hostname",
+			body: `{"heartbeat":{"monitorID":5,"status":2},"monitor":{"id":5,"name":"Router","type":"ping","hostname":"192.168.1.1"}}`,
+			want: Alert{
+				Status:      "firing",
+				Fingerprint: "uptime-kuma-5",
+				Labels:      map[string]string{"alertname": "Router", "monitor_id": "5", "monitor_type": "ping", "monitor_status": "pending", "severity": "warning", "instance": "192.168.1.1"},
+				Annotations: map[string]string{"title": "Router is pending", "description": "Router is pending", "priority": "5"},
+			},
+		},
+		{
+			name: "test notification",
+			body: `{"heartbeat":null,"monitor":null,"msg":"Uptime Kuma Testing"}`,
+			want: Alert{
+				Status:      "firing",
+				Labels:      map[string]string{"alertname": "UptimeKuma"},
+				Annotations: map[string]string{"title": "Uptime Kuma", "description": "Uptime Kuma Testing"},
+			},
+		},
+		{name: "empty", body: `{}`, wantErr: true},
+		{name: "unknown status", body: `{"heartbeat":{"status":9},"monitor":{"id":1,"name":"x"}}`, wantErr: true},
+		{name: "not json", body: `kuma`, wantErr: true},
+	}
+
+	svr := newInputTestBridge()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := parseKuma(svr, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseKuma() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				checkInputAlert(t, n, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// logLevelState is the level of the default logger. It starts at the level of the flags and
+// can be changed at runtime through /-/log_level, optionally only for a while
+type logLevelState struct {
+	level      slog.LevelVar
+	configured slog.Level
+
+	mu         sync.Mutex
+	timer      *time.Timer
+	until      time.Time
+	generation int
+}
+
+type logLevelStatus struct {
+	Level      string     `json:"level"`
+	Configured string     `json:"configured"`
+	Until      *time.Time `json:"until,omitempty"`
+}
+
+var runtimeLogLevel = &logLevelState{}
+
+// set changes the level, returning to the configured level after the duration unless it is 0
+func (l *logLevelState) set(level slog.Level, duration time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	/* A timer that fired but waits for the lock must not undo this change */
+	l.generation++
+	l.until = time.Time{}
+	if duration > 0 && level != l.configured {
+		generation := l.generation
+		l.until = time.Now().Add(duration)
+		l.timer = time.AfterFunc(duration, func() { l.restore(generation) })
+	}
+
+	previous := l.level.Level()
+	l.level.Set(level)
+	if previous != level {
+		slog.Warn("Log level changed", "level", level, "previous_level", previous, "duration", duration)
+	}
+}
+
+/* Returns to the configured level once a level set for a while expired, unless it was changed since */
+func (l *logLevelState) restore(generation int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if generation != l.generation {
+		return
+	}
+	l.timer = nil
+	l.until = time.Time{}
+	l.level.Set(l.configured)
+	slog.Warn("Log level restored", "level", l.configured)
+}
+
+func (l *logLevelState) status() logLevelStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status := logLevelStatus{Level: l.level.Level().String(), Configured: l.configured.String()}
+	if !l.until.IsZero() {
+		until := l.until
+		status.Until = &until
+	}
+	return status
+}
+
+// debugLogging reports whether debug messages are logged right now
+func debugLogging() bool {
+	return runtimeLogLevel.level.Level() <= slog.LevelDebug
+}
+
+// setupLogging replaces the default logger with one honoring --log_level and --log_format,
+// which redacts secrets from every line. The --debug flag is kept as a shortcut for
+// --log_level=debug
+func setupLogging() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("ERROR: invalid log level %s: %s\n", *logLevel, err))
+		os.Exit(1)
+	}
+
+	if *debug {
+		level = slog.LevelDebug
+	}
+	*debug = level == slog.LevelDebug
+	runtimeLogLevel.configured = level
+	runtimeLogLevel.level.Set(level)
+
+	opts := &slog.HandlerOptions{Level: &runtimeLogLevel.level, ReplaceAttr: redactAttr}
+	var handler slog.Handler
+	if *logFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// handleLogLevel changes the log level on POST to the one given as ?level=debug, optionally
+// only for the duration given as ?duration=15m, and returns the current level on GET
+func (svr *bridge) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var level slog.Level
+		if val := r.URL.Query().Get("level"); val == "" {
+			level = runtimeLogLevel.configured
+		} else if err := level.UnmarshalText([]byte(val)); err != nil {
+			http.Error(w, "Invalid level: "+val, http.StatusBadRequest)
+			return
+		}
+		var duration time.Duration
+		if val := r.URL.Query().Get("duration"); val != "" {
+			var err error
+			if duration, err = time.ParseDuration(val); err != nil || duration < 0 {
+				http.Error(w, "Invalid duration: "+val, http.StatusBadRequest)
+				return
+			}
+		}
+		runtimeLogLevel.set(level, duration)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, runtimeLogLevel.status())
+}
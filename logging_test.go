@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLogLevelExpires(t *testing.T) {
+	l := &logLevelState{configured: slog.LevelInfo}
+	l.set(slog.LevelDebug, 10*time.Millisecond)
+	if status := l.status(); status.Level != "DEBUG" || status.Until == nil {
+		t.Fatalf("status() = %+v, want DEBUG until a time", status)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for l.level.Level() != slog.LevelInfo && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status := l.status(); status.Level != "INFO" || status.Until != nil {
+		t.Errorf("status() after the duration = %+v, want INFO", status)
+	}
+}
+
+func TestLogLevelStaleRestore(t *testing.T) {
+	l := &logLevelState{configured: slog.LevelInfo}
+	l.set(slog.LevelDebug, time.Hour)
+	stale := l.generation
+
+	/* As if the timer of the first change fired right as the level was set again */
+	l.set(slog.LevelWarn, time.Hour)
+	l.restore(stale)
+	if got := l.level.Level(); got != slog.LevelWarn {
+		t.Errorf("level after a stale restore = %s, want WARN", got)
+	}
+
+	l.set(slog.LevelError, 0)
+	l.restore(stale + 1)
+	if got := l.level.Level(); got != slog.LevelError {
+		t.Errorf("level after the restore of a replaced timer = %s, want ERROR", got)
+	}
+	if l.status().Until != nil {
+		t.Error("level set without duration expires")
+	}
+}
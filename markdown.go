@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// formatMarkdownDetails wraps a rendered message in Markdown: the status line above the
+// message and a table of the alert labels below it. The status line is skipped when empty and
+// the link to the generator when the caller already adds it (as --extended_details does)
+func formatMarkdownDetails(alert Alert, message string, statusLine string, withLink bool) string {
+	var b strings.Builder
+
+	if statusLine != "" {
+		b.WriteString(statusLine + "\n\n")
+	}
+	b.WriteString(message)
+
+	if len(alert.Labels) > 0 {
+		b.WriteString("\n\n" + markdownTable("Label", alert.Labels))
+	}
+
+	if withLink && strings.HasPrefix(alert.GeneratorURL, "http") {
+		b.WriteString("\n\n[Go to source](" + alert.GeneratorURL + ")")
+	}
+	return b.String()
+}
+
+// markdownTable renders a two column table of the entries sorted by key, headed by the name
+// of the key column
+func markdownTable(keyColumn string, entries map[string]string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("| %s | Value |\n|---|---|", keyColumn))
+	for _, key := range sortedKeys(entries) {
+		b.WriteString(fmt.Sprintf("\n| %s | %s |", escapeMarkdownTableCell(key), escapeMarkdownTableCell(entries[key])))
+	}
+	return b.String()
+}
+
+func sortedKeys(entries map[string]string) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/* Pipes end a table cell and newlines end the row, so neither may appear in a cell */
+func escapeMarkdownTableCell(s string) string {
+	if *escapeHTML {
+		s = html.EscapeString(s)
+	}
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelMatcher matches a single label of an alert, using the operators of Alertmanager
+// matchers: =, !=, =~ and !~. Regular expressions are anchored like in Alertmanager
+type labelMatcher struct {
+	name  string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+// matcherSet is a list of matchers that all have to match an alert
+type matcherSet []labelMatcher
+
+// parseMatcherSet reads comma separated matchers such as severity=info,team=~"infra|db".
+// Values may be quoted, and a missing label matches like an empty one
+func parseMatcherSet(spec string) (matcherSet, error) {
+	set := matcherSet{}
+	for _, raw := range strings.Split(spec, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		idx := strings.IndexAny(raw, "=!")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid matcher '%s' - expected NAME=VALUE, NAME!=VALUE, NAME=~REGEX or NAME!~REGEX", raw)
+		}
+
+		m := labelMatcher{name: strings.TrimSpace(raw[:idx])}
+		rest := raw[idx:]
+		for _, op := range []string{"=~", "!~", "!=", "="} {
+			if strings.HasPrefix(rest, op) {
+				m.op = op
+				m.value = strings.Trim(strings.TrimSpace(rest[len(op):]), `"`)
+				break
+			}
+		}
+		if m.op == "" {
+			return nil, fmt.Errorf("invalid operator in matcher '%s'", raw)
+		}
+
+		if m.op == "=~" || m.op == "!~" {
+			re, err := regexp.Compile("^(?:" + m.value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression in matcher '%s': %w", raw, err)
+			}
+			m.re = re
+		}
+		set = append(set, m)
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("empty matcher '%s'", spec)
+	}
+	return set, nil
+}
+
+func parseMatcherSets(specs []string) ([]matcherSet, error) {
+	sets := []matcherSet{}
+	for _, spec := range specs {
+		set, err := parseMatcherSet(spec)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+func (m labelMatcher) matches(labels map[string]string) bool {
+	value := labels[m.name]
+	switch m.op {
+	case "=":
+		return value == m.value
+	case "!=":
+		return value != m.value
+	case "=~":
+		return m.re.MatchString(value)
+	default:
+		return !m.re.MatchString(value)
+	}
+}
+
+func (s matcherSet) matches(labels map[string]string) bool {
+	for _, m := range s {
+		if !m.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// anyMatches reports whether at least one of the matcher sets matches the labels
+func anyMatches(sets []matcherSet, labels map[string]string) bool {
+	for _, s := range sets {
+		if s.matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropAlert reports whether an alert is filtered out by its status (--skip_resolved and
+// --resolved_only) or its labels (--ignore_matcher and --only_matcher)
+func (svr *bridge) dropAlert(alert Alert) bool {
+	if *svr.skipResolved && alert.Status == "resolved" {
+		return true
+	}
+	if *svr.resolvedOnly && alert.Status != "resolved" {
+		return true
+	}
+
+	if anyMatches(svr.ignoreMatchers, alert.Labels) {
+		return true
+	}
+	return len(svr.onlyMatchers) > 0 && !anyMatches(svr.onlyMatchers, alert.Labels)
+}
@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func TestParseMatcherSet(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []labelMatcher
+		wantErr bool
+	}{
+		{spec: "severity=info", want: []labelMatcher{{name: "severity", op: "=", value: "info"}}},
+		{spec: `team=~"infra|db"`, want: []labelMatcher{{name: "team", op: "=~", value: "infra|db"}}},
+		{spec: "severity = info, env!=prod", want: []labelMatcher{{name: "severity", op: "=", value: "info"}, {name: "env", op: "!=", value: "prod"}}},
+		{spec: "job!~node.*,", want: []labelMatcher{{name: "job", op: "!~", value: "node.*"}}},
+		{spec: "instance=", want: []labelMatcher{{name: "instance", op: "=", value: ""}}},
+		{spec: "", wantErr: true},
+		{spec: " , ", wantErr: true},
+		{spec: "severity", wantErr: true},
+		{spec: "=info", wantErr: true},
+		{spec: "severity!info", wantErr: true},
+		{spec: "team=~(infra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseMatcherSet(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMatcherSet(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseMatcherSet(%q) = %d matchers, want %d", tt.spec, len(got), len(tt.want))
+			}
+			for i, m := range got {
+				w := tt.want[i]
+				if m.name != w.name || m.op != w.op || m.value != w.value {
+					t.Errorf("parseMatcherSet(%q)[%d] = %s%s%q, want %s%s%q", tt.spec, i, m.name, m.op, m.value, w.name, w.op, w.value)
+				}
+			}
+		})
+	}
+}
+
+func TestMatcherSetMatches(t *testing.T) {
+	labels := map[string]string{"alertname": "NodeDown", "severity": "critical", "team": "infra"}
+
+	/* An empty value matches missing labels, like in Alertmanager */
+	for spec, want := range map[string]bool{
+		"severity=critical":            true,
+		"severity=warning":             false,
+		"severity!=warning":            true,
+		"team=~infra|db":               true,
+		"team=~inf":                    false,
+		"alertname!~Node.*":            false,
+		"env=":                         true,
+		"env!=":                        false,
+		"env=~.*":                      true,
+		"severity=critical,team=db":    false,
+		"severity=critical,team=infra": true,
+	} {
+		set, err := parseMatcherSet(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := set.matches(labels); got != want {
+			t.Errorf("matches(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
+
+func TestDropAlert(t *testing.T) {
+	ignore, err := parseMatcherSets([]string{"severity=info", "alertname=Watchdog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	only, err := parseMatcherSets([]string{"team=infra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		skipResolved bool
+		resolvedOnly bool
+		ignore       []matcherSet
+		only         []matcherSet
+		alert        Alert
+		want         bool
+	}{
+		{name: "no filters", alert: Alert{Status: "firing"}, want: false},
+		{name: "skip resolved", skipResolved: true, alert: Alert{Status: "resolved"}, want: true},
+		{name: "skip resolved keeps firing", skipResolved: true, alert: Alert{Status: "firing"}, want: false},
+		{name: "resolved only", resolvedOnly: true, alert: Alert{Status: "firing"}, want: true},
+		{name: "ignored", ignore: ignore, alert: Alert{Status: "firing", Labels: map[string]string{"alertname": "Watchdog"}}, want: true},
+		{name: "not ignored", ignore: ignore, alert: Alert{Status: "firing", Labels: map[string]string{"severity": "critical"}}, want: false},
+		{name: "only matches", only: only, alert: Alert{Status: "firing", Labels: map[string]string{"team": "infra"}}, want: false},
+		{name: "only does not match", only: only, alert: Alert{Status: "firing", Labels: map[string]string{"team": "db"}}, want: true},
+		{name: "ignore wins over only", ignore: ignore, only: only, alert: Alert{Status: "firing", Labels: map[string]string{"team": "infra", "severity": "info"}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr := &bridge{skipResolved: &tt.skipResolved, resolvedOnly: &tt.resolvedOnly, ignoreMatchers: tt.ignore, onlyMatchers: tt.only}
+			if got := svr.dropAlert(tt.alert); got != tt.want {
+				t.Errorf("dropAlert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
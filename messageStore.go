@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type storedMessage struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// messageStore remembers which Gotify message was created for a firing alert, keyed by the
+// alert's fingerprint. When a path is set, the mapping is persisted as JSON so it survives
+// restarts of the bridge.
+type messageStore struct {
+	sync.Mutex
+	path     string
+	messages map[string]storedMessage
+}
+
+func NewMessageStore(path string) (*messageStore, error) {
+	s := &messageStore{
+		path:     path,
+		messages: make(map[string]storedMessage),
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read message store %s: %w", path, err)
+	}
+
+	if len(b) > 0 {
+		if err = json.Unmarshal(b, &s.messages); err != nil {
+			return nil, fmt.Errorf("unable to parse message store %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *messageStore) Get(fingerprint string) (storedMessage, bool) {
+	s.Lock()
+	defer s.Unlock()
+	msg, ok := s.messages[fingerprint]
+	return msg, ok
+}
+
+func (s *messageStore) Put(fingerprint string, msg storedMessage) error {
+	s.Lock()
+	defer s.Unlock()
+	s.messages[fingerprint] = msg
+	return s.save()
+}
+
+func (s *messageStore) Delete(fingerprint string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.messages, fingerprint)
+	return s.save()
+}
+
+/* Callers must hold the lock */
+func (s *messageStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(s.messages)
+	if err != nil {
+		return err
+	}
+
+	/* Write to a temporary file first so a crash never leaves a truncated store behind */
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("unable to write message store %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, s.path)
+}
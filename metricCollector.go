@@ -4,21 +4,203 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/http"
+	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// bridgeInstruments are the metrics that can't be expressed by the plain counters in the
+// metrics map. They are registered alongside the MetricsCollector on every scrape
+type bridgeInstruments struct {
+	requestDuration prometheus.Histogram
+	gotifyDuration  prometheus.Histogram
+	dispatches      *prometheus.CounterVec
+	queueDepth      prometheus.Gauge
+	inFlight        prometheus.Gauge
+	paused          prometheus.Gauge
+}
+
+func NewBridgeInstruments(namespace string) *bridgeInstruments {
+	return &bridgeInstruments{
+		requestDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to handle a webhook request, including all dispatches to gotify",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		gotifyDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "gotify_request_duration_seconds",
+			Help:      "Time taken by a single POST of a message to gotify",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		dispatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "gotify_dispatches_total",
+			Help:      "Messages posted to gotify by outcome (success, client_error, server_error, network_error)",
+		}, []string{"outcome"}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_depth",
+			Help:      "Webhook calls waiting for a worker in --async mode",
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_in_flight",
+			Help:      "Webhook calls currently being processed by a worker in --async mode",
+		}),
+		paused: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "paused",
+			Help:      "Whether the bridge is paused through /-/pause",
+		}),
+	}
+}
+
+func (i *bridgeInstruments) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{i.requestDuration, i.gotifyDuration, i.dispatches, i.queueDepth, i.inFlight, i.paused}
+}
+
+// observeDispatch records the latency and outcome of a POST to gotify. A status code of 0
+// means gotify could not be reached at all
+func (i *bridgeInstruments) observeDispatch(start time.Time, statusCode int) {
+	i.gotifyDuration.Observe(time.Since(start).Seconds())
+
+	outcome := "success"
+	switch {
+	case statusCode == 0:
+		outcome = "network_error"
+	case statusCode >= 500:
+		outcome = "server_error"
+	case statusCode >= 400:
+		outcome = "client_error"
+	}
+	i.dispatches.WithLabelValues(outcome).Inc()
+}
+
+// alertMetricKey identifies a per-alert counter broken down by the status and severity of
+// the alerts it counts
+type alertMetricKey struct {
+	name     string
+	status   string
+	severity string
+}
+
+// metricStore holds the counters of the bridge. Handlers update it concurrently while the
+// collector reads it, so all access goes through the lock
+type metricStore struct {
+	sync.Mutex
+	counters map[string]int
+	alerts   map[alertMetricKey]int
+	targets  map[string]map[string]int
+	forwards map[string]map[string]int
+}
+
+func newMetricStore() *metricStore {
+	return &metricStore{
+		counters: make(map[string]int),
+		alerts:   make(map[alertMetricKey]int),
+		targets:  make(map[string]map[string]int),
+		forwards: make(map[string]map[string]int),
+	}
+}
+
+// Init makes sure the named counters are exported even before they are first incremented
+func (s *metricStore) Init(names ...string) {
+	s.Lock()
+	defer s.Unlock()
+	for _, name := range names {
+		if _, ok := s.counters[name]; !ok {
+			s.counters[name] = 0
+		}
+	}
+}
+
+func (s *metricStore) Inc(name string) {
+	s.Lock()
+	defer s.Unlock()
+	s.counters[name]++
+}
+
+func (s *metricStore) IncAlert(key alertMetricKey) {
+	s.Lock()
+	defer s.Unlock()
+	s.alerts[key]++
+}
+
+// IncTarget increments a per-target counter. Both the dispatched and failed counters of a
+// target are exported as soon as it is first used
+func (s *metricStore) IncTarget(target string, name string) {
+	s.Lock()
+	defer s.Unlock()
+	counts, ok := s.targets[target]
+	if !ok {
+		counts = map[string]int{"dispatched": 0, "failed": 0}
+		s.targets[target] = counts
+	}
+	counts[name]++
+}
+
+// IncForward increments a per-URL counter of --forward_url. Both the succeeded and failed
+// counters of a URL are exported as soon as it is first used
+func (s *metricStore) IncForward(url string, name string) {
+	s.Lock()
+	defer s.Unlock()
+	counts, ok := s.forwards[url]
+	if !ok {
+		counts = map[string]int{"succeeded": 0, "failed": 0}
+		s.forwards[url] = counts
+	}
+	counts[name]++
+}
+
+/* Copies counters kept per target or URL */
+func copyNested(nested map[string]map[string]int) map[string]map[string]int {
+	copied := make(map[string]map[string]int, len(nested))
+	for outer, counts := range nested {
+		copied[outer] = make(map[string]int, len(counts))
+		for key, value := range counts {
+			copied[outer][key] = value
+		}
+	}
+	return copied
+}
+
+// snapshot returns copies of all counters so they can be collected without holding the lock
+func (s *metricStore) snapshot() (map[string]int, map[alertMetricKey]int, map[string]map[string]int, map[string]map[string]int) {
+	s.Lock()
+	defer s.Unlock()
+
+	counters := make(map[string]int, len(s.counters))
+	for key, value := range s.counters {
+		counters[key] = value
+	}
+	alerts := make(map[alertMetricKey]int, len(s.alerts))
+	for key, value := range s.alerts {
+		alerts[key] = value
+	}
+	return counters, alerts, copyNested(s.targets), copyNested(s.forwards)
+}
+
+// countAlert increments the named per-alert counter for the status and severity of an alert
+func (svr *bridge) countAlert(name string, alert Alert) {
+	metrics.IncAlert(alertMetricKey{
+		name:     name,
+		status:   alert.Status,
+		severity: alert.Labels[*svr.severityLabel],
+	})
+}
+
 type MetricsCollector struct {
-	metrics   *map[string]int
+	metrics   *metricStore
 	svr       *bridge
 	namespace string
 }
 
-func NewMetricsCollector(metrics *map[string]int, svr *bridge, namespace *string) *MetricsCollector {
+func NewMetricsCollector(metrics *metricStore, svr *bridge, namespace *string) *MetricsCollector {
 	return &MetricsCollector{
 		metrics:   metrics,
 		svr:       svr,
@@ -27,7 +209,9 @@ func NewMetricsCollector(metrics *map[string]int, svr *bridge, namespace *string
 }
 
 func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
-	for key, value := range *c.metrics {
+	counters, alerts, targets, forwards := c.metrics.snapshot()
+
+	for key, value := range counters {
 		varDesc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "", key),
 			fmt.Sprintf("Alertmanager-Gotify bridge %s metric", key),
 			nil, nil,
@@ -36,42 +220,62 @@ func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 		ch <- prometheus.MustNewConstMetric(varDesc, prometheus.GaugeValue, float64(value))
 	}
 
-	/* Gather gotify health info */
+	for key, value := range alerts {
+		varDesc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "", key.name),
+			fmt.Sprintf("Alertmanager-Gotify bridge %s metric", key.name),
+			[]string{"status", "severity"}, nil,
+		)
 
-	/* Trim off /message and add /health. Use TrimSuffix instead of ReplaceAll just in case
-	   a user has the string /message in the path (via proxies or whatnot) */
+		ch <- prometheus.MustNewConstMetric(varDesc, prometheus.GaugeValue, float64(value), key.status, key.severity)
+	}
 
-	gotifyUpDesc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "", "gotify_up"),
-		"Base scrape status for Gotify",
-		nil, nil,
-	)
+	for target, counts := range targets {
+		for key, value := range counts {
+			varDesc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "target", key),
+				fmt.Sprintf("Alertmanager-Gotify bridge per-target %s metric", key),
+				nil, prometheus.Labels{"target": target},
+			)
 
-	healthEndpoint := fmt.Sprintf("%s%s", strings.TrimSuffix(*c.svr.gotifyEndpoint, "/message"), "/health")
-	client := http.Client{
-		Timeout: *c.svr.timeout * time.Second,
+			ch <- prometheus.MustNewConstMetric(varDesc, prometheus.GaugeValue, float64(value))
+		}
 	}
-	resp, err := client.Get(healthEndpoint)
 
-	/* Always set these since they seem to be visible in /health all the time */
-	status := map[string]string{"health": "error", "database": "error"}
+	for url, counts := range forwards {
+		for key, value := range counts {
+			varDesc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "forward", key),
+				fmt.Sprintf("Alertmanager-Gotify bridge per-URL forward %s metric", key),
+				nil, prometheus.Labels{"url": url},
+			)
 
-	if err != nil {
-		ch <- prometheus.MustNewConstMetric(gotifyUpDesc, prometheus.GaugeValue, float64(0))
-		log.Printf("Error getting health information from gotify: %v", err)
-	} else {
-		ch <- prometheus.MustNewConstMetric(gotifyUpDesc, prometheus.GaugeValue, float64(1))
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Printf("Error reading health status from gotify response: %v", err)
-		} else {
-			err = json.Unmarshal(body, &status)
-			if err != nil {
-				log.Printf("Invalid JSON returned from gotify: %v", err)
-			}
+			ch <- prometheus.MustNewConstMetric(varDesc, prometheus.GaugeValue, float64(value))
 		}
 	}
 
+	/* Gotify health info is probed in the background, nothing is exported until the first probe */
+	if c.svr.health == nil {
+		return
+	}
+	up, status, checked := c.svr.health.last()
+	if checked.IsZero() {
+		return
+	}
+
+	gotifyUpDesc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "", "gotify_up"),
+		"Base scrape status for Gotify",
+		nil, nil,
+	)
+	if up {
+		ch <- prometheus.MustNewConstMetric(gotifyUpDesc, prometheus.GaugeValue, float64(1))
+	} else {
+		ch <- prometheus.MustNewConstMetric(gotifyUpDesc, prometheus.GaugeValue, float64(0))
+	}
+
+	checkedDesc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "gotify_health", "checked_timestamp_seconds"),
+		"Time Gotify health was last probed, in seconds since the epoch",
+		nil, nil,
+	)
+	ch <- prometheus.MustNewConstMetric(checkedDesc, prometheus.GaugeValue, float64(checked.UnixNano())/1e9)
+
 	for key, value := range status {
 		varDesc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "gotify_health", key),
 			fmt.Sprintf("Gotify health metric '%s'", key),
@@ -85,5 +289,34 @@ func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 }
 
+// gotifyHealth probes the /health endpoint of gotify. up is false when gotify could not be
+// reached, and the status holds "green" for every healthy component
+func (svr *bridge) gotifyHealth() (up bool, status map[string]string) {
+	/* Trim off /message and add /health. Use TrimSuffix instead of ReplaceAll just in case
+	   a user has the string /message in the path (via proxies or whatnot) */
+	healthEndpoint := fmt.Sprintf("%s%s", strings.TrimSuffix(*svr.gotifyEndpoint, "/message"), "/health")
+	resp, err := svr.gotifyClient.Get(healthEndpoint)
+
+	/* Always set these since they seem to be visible in /health all the time */
+	status = map[string]string{"health": "error", "database": "error"}
+
+	if err != nil {
+		slog.Warn("Error getting health information from gotify", "error", err)
+		return false, status
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		slog.Warn("Error reading health status from gotify response", "error", err)
+	} else {
+		err = json.Unmarshal(body, &status)
+		if err != nil {
+			slog.Warn("Invalid JSON returned from gotify", "error", err)
+		}
+	}
+	return true, status
+}
+
 func (c *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
 }
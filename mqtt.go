@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttInput subscribes to topics of an MQTT broker and sends every message published to them
+// to Gotify. Messages are turned into alerts by templates just like the generic input format
+type mqttInput struct {
+	options *mqtt.ClientOptions
+	topics  map[string]byte
+	input   *genericInput
+}
+
+// mqttInputFromFlags creates the MQTT input configured by the --mqtt_* flags
+func mqttInputFromFlags() (*mqttInput, error) {
+	tlsConfig, err := clientTLSConfig(*mqttCAFile, *mqttInsecure)
+	if err != nil {
+		return nil, err
+	}
+	templates := genericConfig{Title: *mqttTitleTemplate, Message: *mqttMessageTemplate, Priority: *mqttPriorityTemplate}
+	return newMQTTInput(*mqttBroker, *mqttTopics, *mqttQoS, *mqttClientID, os.Getenv("MQTT_USERNAME"), os.Getenv("MQTT_PASSWORD"), tlsConfig, templates)
+}
+
+func newMQTTInput(broker string, topics []string, qos int, clientID string, username string, password string, tlsConfig *tls.Config, templates genericConfig) (*mqttInput, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid broker URL '%s'", broker)
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("at least one topic is required")
+	}
+	if qos < 0 || qos > 2 {
+		return nil, fmt.Errorf("the QoS must be 0, 1 or 2")
+	}
+	input, err := newGenericInput(templates)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mqttInput{topics: map[string]byte{}, input: input}
+	for _, topic := range topics {
+		m.topics[topic] = byte(qos)
+	}
+	/* With a persistent session, the broker keeps messages of QoS 1 and 2 while the bridge is down */
+	m.options = mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetTLSConfig(tlsConfig).
+		SetCleanSession(qos == 0).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second)
+	return m, nil
+}
+
+func (m *mqttInput) run(svr *bridge) {
+	m.options.SetOnConnectHandler(func(client mqtt.Client) {
+		/* Subscriptions are renewed on every connect, the broker may have lost the session */
+		token := client.SubscribeMultiple(m.topics, func(_ mqtt.Client, msg mqtt.Message) {
+			svr.receiveMQTT(m, msg)
+		})
+		if token.Wait(); token.Error() != nil {
+			slog.Error("Unable to subscribe to MQTT topics", "error", token.Error())
+			return
+		}
+		slog.Info("Subscribed to MQTT topics", "broker", m.options.Servers[0].Redacted(), "topics", len(m.topics))
+	})
+	m.options.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		slog.Warn("Connection to MQTT broker lost - reconnecting", "error", err)
+	})
+
+	client := mqtt.NewClient(m.options)
+	client.Connect()
+}
+
+// receiveMQTT dispatches a message of a subscribed topic. Messages are acknowledged once this
+// returns, so messages with QoS 1 or 2 are only lost when dispatching them failed
+func (svr *bridge) receiveMQTT(m *mqttInput, msg mqtt.Message) {
+	metrics.Inc("requests_received")
+	id := newRequestID()
+	ctx := context.WithValue(context.Background(), requestIDKey{}, id)
+	log := contextLogger(ctx).With("topic", msg.Topic())
+
+	doc := map[string]interface{}{"topic": msg.Topic(), "payload": string(msg.Payload())}
+	decoder := json.NewDecoder(bytes.NewReader(msg.Payload()))
+	decoder.UseNumber()
+	var decoded interface{}
+	if decoder.Decode(&decoded) == nil {
+		doc["json"] = decoded
+	}
+
+	alert, err := svr.genericAlert(m.input, doc, "MQTT")
+	if err != nil {
+		log.Warn("Unable to convert MQTT message", "error", err)
+		metrics.Inc("requests_invalid")
+		return
+	}
+	alert.Labels["topic"] = msg.Topic()
+	notification := singleAlert("mqtt", "mqtt:"+msg.Topic(), alert)
+
+	respCode, text := svr.deliver(ctx, svr.gotifyToken.Get(), notification, msg.Payload())
+	if respCode >= http.StatusBadRequest {
+		log.Warn("Unable to dispatch MQTT message", "status", respCode, "error", strings.Join(text, "; "))
+	}
+}
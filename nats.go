@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsInput subscribes to a subject of NATS and dispatches every message like a webhook call
+// to path. Replicas of the bridge share the messages as members of a queue group. Core NATS
+// delivers messages at most once, with a stream of JetStream the messages are acknowledged
+// once dispatched and delivered again otherwise
+type natsInput struct {
+	url     string
+	subject string
+	queue   string
+	stream  string
+	durable string
+	path    string
+	options []nats.Option
+	backoff time.Duration
+}
+
+// natsInputFromFlags creates the NATS input configured by the --nats_* flags
+func natsInputFromFlags() (*natsInput, error) {
+	var tlsConfig *tls.Config
+	if *natsCAFile != "" {
+		var err error
+		if tlsConfig, err = clientTLSConfig(*natsCAFile, false); err != nil {
+			return nil, err
+		}
+	}
+	return newNATSInput(*natsURL, *natsSubject, *natsQueue, *natsStream, *natsDurable, *natsPath, tlsConfig, os.Getenv("NATS_USERNAME"), os.Getenv("NATS_PASSWORD"), os.Getenv("NATS_TOKEN"))
+}
+
+func newNATSInput(url string, subject string, queue string, stream string, durable string, path string, tlsConfig *tls.Config, username string, password string, token string) (*natsInput, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("the subject is required")
+	}
+	if stream != "" && durable == "" {
+		return nil, fmt.Errorf("a durable consumer name is required for JetStream")
+	}
+	options := []nats.Option{}
+	if username != "" {
+		options = append(options, nats.UserInfo(username, password))
+	}
+	if token != "" {
+		options = append(options, nats.Token(token))
+	}
+	if tlsConfig != nil {
+		options = append(options, nats.Secure(tlsConfig))
+	}
+	options = append(options,
+		nats.Name("alertmanager_gotify_bridge"),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			slog.Warn("Connection to NATS lost - reconnecting", "error", err)
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			slog.Info("Reconnected to NATS", "server", conn.ConnectedUrlRedacted())
+		}),
+	)
+	return &natsInput{url: url, subject: subject, queue: queue, stream: stream, durable: durable, path: path, options: options, backoff: 10 * time.Second}, nil
+}
+
+func (n *natsInput) run(svr *bridge) {
+	target, err := svr.pathBridge(n.path)
+	var conn *nats.Conn
+	if err == nil {
+		conn, err = nats.Connect(n.url, n.options...)
+	}
+	if err != nil {
+		slog.Error("Unable to subscribe to NATS", "subject", n.subject, "error", err)
+		os.Exit(1)
+	}
+
+	/* JetStream can only be subscribed to once connected, NATS keeps core subscriptions until then */
+	for {
+		if err = n.subscribe(conn, target); err == nil {
+			return
+		}
+		slog.Warn("Unable to subscribe to NATS - retrying", "subject", n.subject, "error", err)
+		time.Sleep(n.backoff)
+	}
+}
+
+func (n *natsInput) subscribe(conn *nats.Conn, target *bridge) error {
+	log := slog.With("subject", n.subject)
+
+	if n.stream == "" {
+		_, err := conn.QueueSubscribe(n.subject, n.queue, func(msg *nats.Msg) {
+			if target.consumeMessage(log, msg.Data) {
+				log.Warn("Message is lost - core NATS can't deliver it again, use --nats_stream for that")
+			}
+		})
+		if err == nil {
+			log.Info("Subscribed to NATS", "queue_group", n.queue, "format", *target.inputFormat)
+		}
+		return err
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		return err
+	}
+	/* Dispatching may take a while when Gotify is slow, the message must not be redelivered meanwhile */
+	_, err = js.QueueSubscribe(n.subject, n.queue, func(msg *nats.Msg) {
+		if target.consumeMessage(log, msg.Data) {
+			msg.NakWithDelay(n.backoff)
+			return
+		}
+		if err := msg.Ack(); err != nil {
+			log.Warn("Unable to acknowledge message - it may be dispatched again", "error", err)
+		}
+	}, nats.BindStream(n.stream), nats.Durable(n.durable), nats.ManualAck(), nats.AckWait(time.Minute), nats.DeliverNew())
+	if err == nil {
+		log.Info("Subscribed to NATS JetStream", "stream", n.stream, "durable", n.durable, "queue_group", n.queue, "format", *target.inputFormat)
+	}
+	return err
+}
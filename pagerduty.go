@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const pagerdutyPath = "/v2/enqueue"
+
+// pagerdutyEvent is an event of the Events API v2 of PagerDuty
+type pagerdutyEvent struct {
+	RoutingKey  string `json:"routing_key"`
+	EventAction string `json:"event_action"`
+	DedupKey    string `json:"dedup_key"`
+	Payload     *struct {
+		Summary       string          `json:"summary"`
+		Source        string          `json:"source"`
+		Severity      string          `json:"severity"`
+		Timestamp     string          `json:"timestamp"`
+		Component     string          `json:"component"`
+		Group         string          `json:"group"`
+		Class         string          `json:"class"`
+		CustomDetails json.RawMessage `json:"custom_details"`
+	} `json:"payload"`
+	Client    string `json:"client"`
+	ClientURL string `json:"client_url"`
+	Links     []struct {
+		Href string `json:"href"`
+		Text string `json:"text"`
+	} `json:"links"`
+	Images []struct {
+		Src string `json:"src"`
+	} `json:"images"`
+}
+
+/* Severities of PagerDuty along with the priority they are sent with */
+var pagerdutySeverities = map[string]int{"critical": 8, "error": 7, "warning": 5, "info": 2}
+
+// handlePagerduty implements the Events API v2 of PagerDuty, so software that can only open
+// PagerDuty incidents can reach Gotify. The routing key takes the place of the Gotify token
+// and the dedup key becomes the fingerprint, so resolve events work with --on_resolve
+func (svr *bridge) handlePagerduty(w http.ResponseWriter, r *http.Request) {
+	log := contextLogger(r.Context())
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	if *maxRequestBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, *maxRequestBytes)
+	}
+	body, err := decodeBody(r, body, *maxRequestBytes)
+	var b []byte
+	if err == nil {
+		b, err = io.ReadAll(body)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		rejectTooLarge(log, w, r, tooLarge)
+		return
+	}
+	var event pagerdutyEvent
+	if err == nil {
+		if err = json.Unmarshal(b, &event); err != nil {
+			err = fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+	if err == nil && event.DedupKey == "" && event.EventAction == "trigger" {
+		/* Like PagerDuty, the key of events without one is generated and returned */
+		event.DedupKey = newRequestID()
+	}
+	var notification Notification
+	if err == nil {
+		notification, err = svr.pagerdutyNotification(event)
+	}
+	if err != nil {
+		log.Warn("Invalid PagerDuty event", "error", err, "remote_addr", r.RemoteAddr)
+		metrics.Inc("requests_invalid")
+		writePagerdutyResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"status": "invalid event", "message": "Event object is invalid", "errors": []string{err.Error()},
+		})
+		return
+	}
+
+	if len(notification.Alerts) == 0 {
+		log.Debug("Acknowledge events are not sent to Gotify", "dedup_key", event.DedupKey)
+	} else if respCode, text := svr.deliver(r.Context(), svr.keyToken(event.RoutingKey), notification, b); respCode >= 300 {
+		writePagerdutyResponse(w, respCode, map[string]interface{}{"status": "error", "message": strings.Join(text, "\n")})
+		return
+	}
+	writePagerdutyResponse(w, http.StatusAccepted, map[string]interface{}{"status": "success", "message": "Event processed", "dedup_key": event.DedupKey})
+}
+
+// pagerdutyNotification converts a trigger event into a firing alert and a resolve event into
+// the resolved alert. Acknowledge events give a notification without alerts
+func (svr *bridge) pagerdutyNotification(event pagerdutyEvent) (Notification, error) {
+	alert := Alert{
+		Labels:      map[string]string{"alertname": "PagerDuty"},
+		Annotations: map[string]string{},
+		Fingerprint: event.DedupKey,
+		StartsAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+
+	switch event.EventAction {
+	case "trigger":
+	case "acknowledge":
+		if event.DedupKey == "" {
+			return Notification{}, fmt.Errorf("dedup_key is required for acknowledge events")
+		}
+		return Notification{}, nil
+	case "resolve":
+		if event.DedupKey == "" {
+			return Notification{}, fmt.Errorf("dedup_key is required for resolve events")
+		}
+		/* Resolve events carry no payload, the title of the message sent for the trigger is reused */
+		title := event.DedupKey
+		if original, ok := svr.messages.Get(event.DedupKey); ok && original.Title != "" {
+			title = original.Title
+		}
+		alert.Status, alert.EndsAt, alert.StartsAt = "resolved", alert.StartsAt, ""
+		alert.Annotations[*svr.titleAnnotation] = title
+		alert.Annotations[*svr.messageAnnotation] = "Resolved"
+		return singleAlert("pagerduty", "pagerduty:"+event.DedupKey, alert), nil
+	default:
+		return Notification{}, fmt.Errorf("event_action must be trigger, acknowledge or resolve")
+	}
+
+	payload := event.Payload
+	if payload == nil || payload.Summary == "" || payload.Source == "" {
+		return Notification{}, fmt.Errorf("payload with summary and source is required for trigger events")
+	}
+	priority, ok := pagerdutySeverities[payload.Severity]
+	if !ok {
+		return Notification{}, fmt.Errorf("payload.severity must be critical, error, warning or info")
+	}
+	alert.Status = "firing"
+	alert.Labels[*svr.severityLabel] = payload.Severity
+	alert.Labels["instance"] = payload.Source
+	for name, value := range map[string]string{"component": payload.Component, "group": payload.Group, "class": payload.Class} {
+		if value != "" {
+			alert.Labels[name] = value
+		}
+	}
+	if stamp, err := time.Parse(time.RFC3339, payload.Timestamp); err == nil {
+		alert.StartsAt = stamp.UTC().Format(time.RFC3339)
+	}
+	if strings.HasPrefix(event.ClientURL, "http") {
+		alert.GeneratorURL = event.ClientURL
+	}
+
+	parts := []string{payload.Summary, pagerdutyDetails(payload.CustomDetails)}
+	links := []string{}
+	for _, link := range event.Links {
+		if link.Text != "" {
+			links = append(links, fmt.Sprintf("%s: %s", link.Text, link.Href))
+		} else {
+			links = append(links, link.Href)
+		}
+	}
+	parts = append(parts, strings.Join(links, "\n"))
+	alert.Annotations[*svr.titleAnnotation] = payload.Summary
+	alert.Annotations[*svr.messageAnnotation] = joinParts(parts)
+	if len(event.Images) > 0 && *imageAnnotation != "" {
+		alert.Annotations[*imageAnnotation] = event.Images[0].Src
+	}
+	svr.inputPriority(alert, priority)
+
+	return singleAlert("pagerduty", "pagerduty:"+event.DedupKey, alert), nil
+}
+
+/* Custom details may be any JSON value, objects are listed as key: value lines */
+func pagerdutyDetails(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return string(raw)
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := []string{}
+	for _, key := range keys {
+		value := string(object[key])
+		json.Unmarshal(object[key], &value)
+		lines = append(lines, fmt.Sprintf("%s: %s", key, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func writePagerdutyResponse(w http.ResponseWriter, code int, response map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
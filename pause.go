@@ -0,0 +1,138 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pauseState is the maintenance mode of the bridge. While paused, webhooks are still accepted,
+// but their notifications are held back until the bridge resumes or dropped
+type pauseState struct {
+	drop  bool
+	gauge prometheus.Gauge
+	held  heldMessages
+
+	mu     sync.Mutex
+	paused bool
+	until  time.Time
+}
+
+type pauseStatus struct {
+	Paused bool       `json:"paused"`
+	Until  *time.Time `json:"until,omitempty"`
+	Held   int        `json:"held"`
+}
+
+func newPauseState(drop bool, size int, gauge prometheus.Gauge) *pauseState {
+	return &pauseState{drop: drop, gauge: gauge, held: heldMessages{size: size}}
+}
+
+// active reports whether the bridge is paused. It is false when pausing is not possible
+func (p *pauseState) active() bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// pause pauses the bridge for the given duration, or until it is resumed when it is 0
+func (p *pauseState) pause(duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused = true
+	p.until = time.Time{}
+	if duration > 0 {
+		p.until = time.Now().Add(duration)
+	}
+	p.gauge.Set(1)
+	slog.Warn("Bridge paused", "duration", duration, "drop", p.drop)
+}
+
+// resume ends the pause and sends the notifications held back during it in the background,
+// so that resuming doesn't wait for Gotify
+func (p *pauseState) resume() {
+	p.mu.Lock()
+	wasPaused := p.paused
+	p.paused = false
+	p.until = time.Time{}
+	p.gauge.Set(0)
+	p.mu.Unlock()
+
+	if !wasPaused {
+		return
+	}
+	held := p.held.release()
+	slog.Warn("Bridge resumed - sending held notifications", "count", len(held))
+	go sendHeld(held)
+}
+
+func (p *pauseState) status() pauseStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := pauseStatus{Paused: p.paused}
+	if !p.until.IsZero() {
+		until := p.until
+		status.Until = &until
+	}
+
+	status.Held = p.held.count()
+	return status
+}
+
+// run resumes the bridge once a pause with a duration is over, until the bridge exits
+func (p *pauseState) run() {
+	for range time.Tick(time.Second) {
+		p.mu.Lock()
+		expired := p.paused && !p.until.IsZero() && time.Now().After(p.until)
+		p.mu.Unlock()
+
+		if expired {
+			p.resume()
+		}
+	}
+}
+
+// handlePause pauses the bridge on POST, optionally for the duration given as ?duration=30m,
+// and returns the current pause status on GET
+func (svr *bridge) handlePause(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var duration time.Duration
+		if val := r.URL.Query().Get("duration"); val != "" {
+			var err error
+			if duration, err = time.ParseDuration(val); err != nil || duration < 0 {
+				http.Error(w, "Invalid duration: "+val, http.StatusBadRequest)
+				return
+			}
+		}
+		svr.pause.pause(duration)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	svr.writePauseStatus(w)
+}
+
+// handleResume resumes the bridge on POST
+func (svr *bridge) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	svr.pause.resume()
+	svr.writePauseStatus(w)
+}
+
+func (svr *bridge) writePauseStatus(w http.ResponseWriter) {
+	writeJSON(w, svr.pause.status())
+}
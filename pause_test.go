@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var parseFlagDefaults sync.Once
+
+/* A bridge with the default flags, processing webhook calls for a fake gotify */
+func newWebhookTestBridge(t *testing.T, gotify *fakeGotify) *bridge {
+	t.Helper()
+	parseFlagDefaults.Do(func() {
+		if _, err := kingpin.CommandLine.Parse(nil); err != nil {
+			t.Fatalf("unable to parse the default flags: %v", err)
+		}
+	})
+
+	svr := newBridge(nil)
+	endpoint := gotify.URL + "/message"
+	svr.gotifyEndpoint = &endpoint
+	svr.gotifyToken = newTokenSource("default-token")
+	svr.messages, _ = NewMessageStore("")
+	svr.pause = newPauseState(false, 10, svr.instruments.paused)
+	svr.replay = newReplayQueue(10, 0, "")
+	return svr
+}
+
+func webhookTestCall(alerts ...Alert) Notification {
+	for i := range alerts {
+		if alerts[i].Labels == nil {
+			alerts[i].Labels = map[string]string{"alertname": alerts[i].Fingerprint}
+		}
+		alerts[i].Annotations = map[string]string{"summary": alerts[i].Fingerprint, "description": alerts[i].Status}
+	}
+	return Notification{Status: alerts[0].Status, Receiver: "gotify", Alerts: alerts}
+}
+
+/* Waits for the notifications sent in the background */
+func waitForTokens(t *testing.T, gotify *fakeGotify, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(gotify.received()) < n && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return gotify.received()
+}
+
+func TestPauseHoldsNotifications(t *testing.T) {
+	gotify := newFakeGotify(t, 200, 1)
+	svr := newWebhookTestBridge(t, gotify)
+	svr.pause.pause(0)
+
+	code, text := svr.processNotification(context.Background(), "token-a", webhookTestCall(Alert{Fingerprint: "a1", Status: "firing"}, Alert{Fingerprint: "a2", Status: "firing"}), nil)
+	if code != http.StatusOK || !strings.Contains(strings.Join(text, "\n"), "held while paused") {
+		t.Fatalf("processNotification() while paused = %d %v", code, text)
+	}
+	svr.processNotification(context.Background(), "token-b", webhookTestCall(Alert{Fingerprint: "a3", Status: "firing"}), nil)
+
+	/* The resolved alert cancels its held firing notification */
+	_, text = svr.processNotification(context.Background(), "token-a", webhookTestCall(Alert{Fingerprint: "a2", Status: "resolved"}), nil)
+	if !strings.Contains(text[0], "resolved while held") {
+		t.Errorf("resolved alert while its firing one is held: %v", text)
+	}
+	if status := svr.pause.status(); !status.Paused || status.Held != 2 {
+		t.Errorf("status() = %+v, want paused with 2 held", status)
+	}
+	if got := gotify.received(); len(got) != 0 {
+		t.Fatalf("gotify received %v while paused", got)
+	}
+
+	svr.pause.resume()
+	got := waitForTokens(t, gotify, 2)
+	if len(got) != 2 || got[0] != "token-a" || got[1] != "token-b" {
+		t.Errorf("sent after resuming with tokens %v, want [token-a token-b]", got)
+	}
+	if status := svr.pause.status(); status.Paused || status.Held != 0 {
+		t.Errorf("status() after resuming = %+v", status)
+	}
+
+	svr.processNotification(context.Background(), "token-c", webhookTestCall(Alert{Fingerprint: "a4", Status: "firing"}), nil)
+	if got := gotify.received(); len(got) != 3 || got[2] != "token-c" {
+		t.Errorf("alert after resuming was not sent right away: %v", got)
+	}
+}
+
+func TestPauseDrops(t *testing.T) {
+	gotify := newFakeGotify(t, 200, 1)
+	svr := newWebhookTestBridge(t, gotify)
+	svr.pause = newPauseState(true, 10, svr.instruments.paused)
+	svr.pause.pause(time.Hour)
+
+	_, text := svr.processNotification(context.Background(), "token", webhookTestCall(Alert{Fingerprint: "a1", Status: "firing"}), nil)
+	if !strings.Contains(text[0], "dropped while paused") {
+		t.Errorf("processNotification() = %v, want the alert dropped", text)
+	}
+	svr.pause.resume()
+	time.Sleep(20 * time.Millisecond)
+	if got := gotify.received(); len(got) != 0 {
+		t.Errorf("dropped alert was sent after resuming: %v", got)
+	}
+}
+
+func TestHeldMessagesLimit(t *testing.T) {
+	held := heldMessages{size: 2}
+	svr := newDispatchTestBridge("http://gotify")
+	for _, fingerprint := range []string{"a1", "a2", "a3"} {
+		held.hold(svr, Alert{Fingerprint: fingerprint, Status: "firing"}, "", GotifyNotification{})
+	}
+
+	released := held.release()
+	if len(released) != 2 || released[0].alert.Fingerprint != "a2" || released[1].alert.Fingerprint != "a3" {
+		t.Errorf("released %+v, want a2 and a3 with the oldest dropped", released)
+	}
+	if held.count() != 0 {
+		t.Error("notifications left after releasing them")
+	}
+}
+
+func TestPauseHandlers(t *testing.T) {
+	svr := newWebhookTestBridge(t, newFakeGotify(t, 200, 1))
+
+	call := func(handler http.HandlerFunc, method string, target string) (int, pauseStatus) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, target, nil))
+		status := pauseStatus{}
+		json.Unmarshal(w.Body.Bytes(), &status)
+		return w.Code, status
+	}
+
+	if code, status := call(svr.handlePause, http.MethodPost, "/-/pause?duration=30m"); code != http.StatusOK || !status.Paused || status.Until == nil {
+		t.Errorf("POST /-/pause?duration=30m = %d %+v", code, status)
+	}
+	if code, _ := call(svr.handlePause, http.MethodPost, "/-/pause?duration=soon"); code != http.StatusBadRequest {
+		t.Errorf("POST /-/pause with an invalid duration = %d, want 400", code)
+	}
+	if code, status := call(svr.handlePause, http.MethodGet, "/-/pause"); code != http.StatusOK || !status.Paused {
+		t.Errorf("GET /-/pause = %d %+v", code, status)
+	}
+	if code, _ := call(svr.handleResume, http.MethodGet, "/-/resume"); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /-/resume = %d, want 405", code)
+	}
+	if code, status := call(svr.handleResume, http.MethodPost, "/-/resume"); code != http.StatusOK || status.Paused || status.Until != nil {
+		t.Errorf("POST /-/resume = %d %+v", code, status)
+	}
+}
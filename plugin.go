@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// pluginRequest is handed to the plugin as JSON for every alert about to be sent
+type pluginRequest struct {
+	Alert        Alert              `json:"alert"`
+	Notification GotifyNotification `json:"notification"`
+}
+
+// pluginResult is returned by the plugin as JSON. An empty result leaves the notification as it is
+type pluginResult struct {
+	/* Takes the place of the rendered notification when set */
+	Notification *GotifyNotification `json:"notification"`
+	Drop         bool                `json:"drop"`
+	/* Name of an application of GOTIFY_APP_TOKEN_<NAME> to send the notification to */
+	App string `json:"app"`
+}
+
+// alertPlugin runs a WebAssembly module for every alert, which may rewrite, drop or route the
+// notification. The module exports alloc(size i32) i32 to reserve memory for the request and
+// transform(ptr i32, len i32) i64 returning the position of the result as ptr<<32 | len. Every
+// call gets a fresh instance, so plugins keep no state between alerts
+type alertPlugin struct {
+	path     string
+	timeout  time.Duration
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+func newAlertPlugin(path string, timeout time.Duration) (*alertPlugin, error) {
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+	compiled, err := runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("unable to compile plugin %s: %w", path, err)
+	}
+	for _, name := range []string{"alloc", "transform"} {
+		if _, ok := compiled.ExportedFunctions()[name]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("plugin %s doesn't export the function %s", path, name)
+		}
+	}
+	return &alertPlugin{path: path, timeout: timeout, runtime: runtime, compiled: compiled}, nil
+}
+
+// transform passes an alert and its rendered notification to the plugin and returns its result
+func (p *alertPlugin) transform(ctx context.Context, alert Alert, outbound GotifyNotification) (result pluginResult, err error) {
+	input, err := json.Marshal(pluginRequest{Alert: alert, Notification: outbound})
+	if err != nil {
+		return result, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	config := wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize").
+		WithStderr(os.Stderr).WithSysWalltime().WithSysNanotime()
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled, config)
+	if err != nil {
+		return result, fmt.Errorf("unable to instantiate plugin: %w", err)
+	}
+	defer mod.Close(ctx)
+
+	allocated, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return result, fmt.Errorf("alloc of plugin failed: %w", err)
+	}
+	ptr := uint32(allocated[0])
+	if !mod.Memory().Write(ptr, input) {
+		return result, fmt.Errorf("alloc of plugin returned memory out of range")
+	}
+
+	returned, err := mod.ExportedFunction("transform").Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return result, fmt.Errorf("transform of plugin failed: %w", err)
+	}
+	outPtr, outLen := uint32(returned[0]>>32), uint32(returned[0])
+	if outLen == 0 {
+		return result, nil
+	}
+	output, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return result, fmt.Errorf("transform of plugin returned memory out of range")
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return result, fmt.Errorf("invalid result of plugin: %w", err)
+	}
+	return result, nil
+}
+
+// applyPlugin runs the plugin for an alert about to be sent and reports whether the plugin
+// dropped it. A failing plugin leaves the notification as it is, so alerts still arrive
+func (svr *bridge) applyPlugin(ctx context.Context, logger *slog.Logger, alert Alert, outbound *GotifyNotification, token *string) (drop bool) {
+	if svr.plugin == nil {
+		return false
+	}
+
+	result, err := svr.plugin.transform(ctx, alert, *outbound)
+	if err != nil {
+		logger.Warn("Plugin failed - sending the notification unchanged", "plugin", svr.plugin.path, "error", err)
+		metrics.Inc("plugin_errors")
+		return false
+	}
+	if result.Drop {
+		return true
+	}
+	if result.Notification != nil {
+		*outbound = *result.Notification
+	}
+	if result.App != "" {
+		named, ok := svr.appTokens[strings.ToLower(result.App)]
+		if !ok {
+			logger.Warn("Plugin routed the alert to an unknown application - using the current token", "app", result.App)
+			return false
+		}
+		logger.Debug("Plugin routed the alert to an application", "app", result.App)
+		*token = named
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// registerPprof serves the runtime profiles of net/http/pprof below /debug/pprof/, protected
+// by the same basic auth as the metrics. The command line is left out as flags may hold secrets
+func registerPprof(mux *http.ServeMux) {
+	mux.Handle("/debug/pprof/", basicAuthHandlerBuilder(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", http.NotFoundHandler())
+	mux.Handle("/debug/pprof/profile", basicAuthHandlerBuilder(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", basicAuthHandlerBuilder(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", basicAuthHandlerBuilder(http.HandlerFunc(pprof.Trace)))
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -24,6 +25,15 @@ import (
 var errNaNOrInf = errors.New("value is NaN or Inf")
 
 var fxns = text_template.FuncMap{
+	"values":      parseValueString,
+	"displayTime": displayTime,
+	"formatTime":  formatTime,
+	"since":       since,
+	"duration":    duration,
+	"urlquery":    urlQuery,
+	"urlDecode":   urlDecode,
+	"pathEscape":  url.PathEscape,
+	"urlJoin":     urlJoin,
 	"first": func(v []interface{}) (interface{}, error) {
 		if len(v) > 0 {
 			return v[0], nil
@@ -112,6 +122,7 @@ var fxns = text_template.FuncMap{
 		}
 		return fmt.Sprintf("%.4g%s", v, prefix), nil
 	},
+	"humanizeBytes": humanizeBytes,
 	"humanizeDuration": func(i interface{}) (string, error) {
 		v, err := convertToFloat(i)
 		if err != nil {
@@ -211,6 +222,8 @@ func convertToFloat(i interface{}) (float64, error) {
 		return float64(v), nil
 	case uint64:
 		return float64(v), nil
+	case time.Duration:
+		return v.Seconds(), nil
 	default:
 		return 0, fmt.Errorf("can't convert %T to float", v)
 	}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type gotifyApplication struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// appProvisioner looks up gotify applications by name and creates those that don't exist yet,
+// so named applications work without configuring a token for each of them. Managing
+// applications needs the client token
+type appProvisioner struct {
+	svr         *bridge
+	perReceiver bool
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func newAppProvisioner(svr *bridge, perReceiver bool) *appProvisioner {
+	return &appProvisioner{svr: svr, perReceiver: perReceiver, tokens: map[string]string{}}
+}
+
+// applicationsEndpoint is the gotify API path of the applications next to the message endpoint
+func (svr *bridge) applicationsEndpoint() string {
+	return fmt.Sprintf("%s%s", strings.TrimSuffix(*svr.gotifyEndpoint, "/message"), "/application")
+}
+
+// listApplications returns all applications of the user the client token belongs to
+func (svr *bridge) listApplications(ctx context.Context) ([]gotifyApplication, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", svr.applicationsEndpoint(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-Gotify-Key", *svr.gotifyClientToken)
+
+	resp, err := svr.gotifyClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("non-200 response from gotify: %s", resp.Status)
+	}
+
+	var apps []gotifyApplication
+	if err = json.NewDecoder(resp.Body).Decode(&apps); err != nil {
+		return nil, fmt.Errorf("invalid JSON returned from gotify: %w", err)
+	}
+	return apps, nil
+}
+
+// createApplication creates a gotify application and returns it along with its token
+func (svr *bridge) createApplication(ctx context.Context, name string) (gotifyApplication, error) {
+	body, _ := json.Marshal(map[string]string{
+		"name":        name,
+		"description": "Created by alertmanager_gotify_bridge",
+	})
+
+	var app gotifyApplication
+	request, err := http.NewRequestWithContext(ctx, "POST", svr.applicationsEndpoint(), bytes.NewBuffer(body))
+	if err != nil {
+		return app, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Gotify-Key", *svr.gotifyClientToken)
+
+	resp, err := svr.gotifyClient.Do(request)
+	if err != nil {
+		return app, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return app, fmt.Errorf("non-200 response from gotify: %s", resp.Status)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&app); err != nil {
+		return app, fmt.Errorf("invalid JSON returned from gotify: %w", err)
+	}
+	return app, nil
+}
+
+// token returns the token of the application with the given name, creating the application
+// when gotify doesn't know it yet. Tokens are cached, so gotify is only asked once per name
+func (p *appProvisioner) token(ctx context.Context, name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if token, ok := p.tokens[name]; ok {
+		return token, nil
+	}
+
+	apps, err := p.svr.listApplications(ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to list gotify applications: %w", err)
+	}
+	for _, app := range apps {
+		if strings.EqualFold(app.Name, name) {
+			slog.Debug("Found gotify application", "app", name)
+			p.tokens[name] = app.Token
+			return app.Token, nil
+		}
+	}
+
+	app, err := p.svr.createApplication(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("unable to create gotify application %s: %w", name, err)
+	}
+	slog.Info("Created gotify application", "app", name)
+	p.tokens[name] = app.Token
+	return app.Token, nil
+}
+
+// servesApps reports whether requests may name an application in their path
+func (svr *bridge) servesApps() bool {
+	return len(svr.appTokens) > 0 || svr.provisioner != nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pushoverPath = "/1/messages.json"
+
+/* Priorities of Pushover from lowest to emergency, normal messages get the default priority */
+var pushoverPriorities = map[string]int{"-2": 0, "-1": 2, "1": 8, "2": 10}
+
+// handlePushover implements the message API of Pushover, so devices that only notify Pushover
+// can reach Gotify by replacing api.pushover.net with the bridge. The token field of the
+// request takes the place of the Gotify application token
+func (svr *bridge) handlePushover(w http.ResponseWriter, r *http.Request) {
+	log := contextLogger(r.Context())
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	if *maxRequestBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, *maxRequestBytes)
+	}
+
+	fields, err := pushoverFields(r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		rejectTooLarge(log, w, r, tooLarge)
+		return
+	}
+	var notification Notification
+	if err == nil {
+		notification, err = svr.pushoverNotification(fields)
+	}
+	if err != nil {
+		log.Warn("Invalid Pushover message", "error", err, "remote_addr", r.RemoteAddr)
+		metrics.Inc("requests_invalid")
+		writePushoverResponse(w, r, http.StatusBadRequest, []string{err.Error()})
+		return
+	}
+
+	respCode, text := svr.deliver(r.Context(), svr.keyToken(fields.Get("token")), notification, []byte(fields.Encode()))
+	if respCode >= 300 {
+		writePushoverResponse(w, r, respCode, text)
+		return
+	}
+	writePushoverResponse(w, r, http.StatusOK, nil)
+}
+
+/* Pushover takes the parameters form or multipart encoded as well as in a JSON object */
+func pushoverFields(r *http.Request) (url.Values, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		var object map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&object); err != nil {
+			return nil, fmt.Errorf("invalid JSON object: %w", err)
+		}
+		fields := r.URL.Query()
+		for name, value := range object {
+			if value != nil {
+				fields.Set(name, fmt.Sprint(value))
+			}
+		}
+		return fields, nil
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			return nil, err
+		}
+	default:
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+	}
+	return r.Form, nil
+}
+
+// pushoverNotification converts a Pushover message into a firing alert. Messages in HTML or
+// monospace are sent as Markdown, the supplementary URL opens when the notification is tapped
+func (svr *bridge) pushoverNotification(fields url.Values) (Notification, error) {
+	message := strings.TrimSpace(fields.Get("message"))
+	if message == "" {
+		return Notification{}, fmt.Errorf("message cannot be blank")
+	}
+	title := fields.Get("title")
+	if title == "" {
+		title = "Pushover"
+	}
+
+	alert := Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "Pushover"},
+		Annotations: map[string]string{*svr.titleAnnotation: title},
+		StartsAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if device := fields.Get("device"); device != "" {
+		alert.Labels["device"] = device
+	}
+	if stamp, err := strconv.ParseInt(fields.Get("timestamp"), 10, 64); err == nil {
+		alert.StartsAt = time.Unix(stamp, 0).UTC().Format(time.RFC3339)
+	}
+
+	markdown := false
+	switch {
+	case fields.Get("html") == "1":
+		message, markdown = pushoverMarkdown(message), true
+	case fields.Get("monospace") == "1":
+		message, markdown = "```\n"+message+"\n```", true
+	}
+	link := fields.Get("url")
+	if link != "" && markdown && fields.Get("url_title") != "" {
+		message += fmt.Sprintf("\n\n[%s](%s)", fields.Get("url_title"), link)
+	}
+	alert.Annotations[*svr.messageAnnotation] = message
+	if *extrasAnnotation != "" {
+		if markdown {
+			alert.Annotations[*extrasAnnotation+"::client::display::contentType"] = "text/markdown"
+		}
+		if link != "" {
+			alert.Annotations[*extrasAnnotation+"::client::notification::click::url"] = link
+		}
+	}
+
+	if value := fields.Get("priority"); value != "" && value != "0" {
+		priority, ok := pushoverPriorities[value]
+		if !ok {
+			return Notification{}, fmt.Errorf("priority is invalid - expected a number from -2 to 2")
+		}
+		svr.inputPriority(alert, priority)
+	}
+
+	return singleAlert("pushover", "pushover:"+alert.Labels["alertname"], alert), nil
+}
+
+var (
+	pushoverLink = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	pushoverTags = regexp.MustCompile(`(?i)</?(b|strong|i|em|u|font)(\s[^>]*)?>`)
+)
+
+// pushoverMarkdown turns the few tags of Pushover messages in HTML into Markdown and resolves
+// entities. Underline and font colors are dropped
+func pushoverMarkdown(s string) string {
+	s = pushoverLink.ReplaceAllString(s, "[$2]($1)")
+	s = pushoverTags.ReplaceAllStringFunc(s, func(tag string) string {
+		name := strings.ToLower(strings.Trim(strings.Fields(tag)[0], "</>"))
+		switch name {
+		case "b", "strong":
+			return "**"
+		case "i", "em":
+			return "*"
+		}
+		return ""
+	})
+	return html.UnescapeString(s)
+}
+
+/* Clients of Pushover look at the status field of the answer */
+func writePushoverResponse(w http.ResponseWriter, r *http.Request, code int, errs []string) {
+	response := map[string]interface{}{"status": 1, "request": ""}
+	if id, ok := r.Context().Value(requestIDKey{}).(string); ok {
+		response["request"] = id
+	}
+	if len(errs) > 0 {
+		response["status"], response["errors"] = 0, errs
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
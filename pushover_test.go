@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestPushoverMarkdown(t *testing.T) {
+	for input, want := range map[string]string{
+		"Disk is full":                     "Disk is full",
+		"Disk is <b>full</b>":              "Disk is **full**",
+		"<STRONG>full</STRONG>":            "**full**",
+		"<i>maybe</i> <em>full</em>":       "*maybe* *full*",
+		"<u>full</u>":                      "full",
+		`<font color="#ff0000">red</font>`: "red",
+		`See <a href="https://grafana/d/1">the dashboard</a>`: "See [the dashboard](https://grafana/d/1)",
+		`<a target="_blank" href="https://x">x</a>`:           "[x](https://x)",
+		"a &lt; b &amp;&amp; c &gt; d":                        "a < b && c > d",
+		/* Tags Pushover doesn't know are left alone */
+		"<span>x</span>": "<span>x</span>",
+	} {
+		if got := pushoverMarkdown(input); got != want {
+			t.Errorf("pushoverMarkdown(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
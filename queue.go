@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// webhookJob is a webhook call accepted in --async mode, waiting to be processed by a worker.
+// The bridge is part of the job since endpoints of --config_file share the queue
+type webhookJob struct {
+	svr          *bridge
+	ctx          context.Context
+	token        string
+	notification Notification
+	body         []byte
+}
+
+// startWorkers enables async mode, processing queued webhook calls with the given number of
+// workers. Up to size calls are queued before requests are rejected
+func (svr *bridge) startWorkers(workers int, size int) {
+	svr.queue = make(chan webhookJob, size)
+	for i := 0; i < workers; i++ {
+		go svr.work(i)
+	}
+	slog.Info("Processing webhooks asynchronously", "workers", workers, "queue_size", size)
+}
+
+// enqueue adds a job to the queue without blocking. It returns false when the queue is full
+func (svr *bridge) enqueue(job webhookJob) bool {
+	select {
+	case svr.queue <- job:
+		svr.instruments.queueDepth.Inc()
+		return true
+	default:
+		return false
+	}
+}
+
+func (svr *bridge) work(id int) {
+	for job := range svr.queue {
+		svr.instruments.queueDepth.Dec()
+		svr.instruments.inFlight.Inc()
+
+		respCode, text := job.svr.processNotification(job.ctx, job.token, job.notification, job.body)
+		contextLogger(job.ctx).Debug("Processed queued webhook", "worker", id, "code", respCode, "result", text)
+
+		svr.instruments.inFlight.Dec()
+	}
+}
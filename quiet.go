@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// quietWindow is a time of day on some weekdays. A window ending before it starts reaches
+// into the next day and belongs to the day it starts on
+type quietWindow struct {
+	days  [7]bool
+	start int
+	end   int
+}
+
+// quietHours suppresses or holds back notifications below a priority during the windows
+type quietHours struct {
+	windows     []quietWindow
+	location    *time.Location
+	minPriority int
+	queue       bool
+	held        heldMessages
+}
+
+// parseQuietHours reads windows such as 22:00-07:00, Sat,Sun 00:00-24:00 or
+// Mon-Fri 22:30-06:30, with days and times in the given timezone
+func parseQuietHours(specs []string, timezone string, minPriority int, action string) (*quietHours, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours timezone: %w", err)
+	}
+
+	q := &quietHours{location: location, minPriority: minPriority, queue: action == "queue"}
+	for _, spec := range specs {
+		window, err := parseQuietWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		q.windows = append(q.windows, window)
+	}
+	return q, nil
+}
+
+func parseQuietWindow(spec string) (quietWindow, error) {
+	window := quietWindow{}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return window, fmt.Errorf("invalid quiet hours '%s' - expected [DAYS] HH:MM-HH:MM", spec)
+	}
+
+	if len(fields) == 1 {
+		window.days = [7]bool{true, true, true, true, true, true, true}
+	} else {
+		for _, part := range strings.Split(fields[0], ",") {
+			from, to, isRange := strings.Cut(part, "-")
+			first, err := parseWeekday(from)
+			if err != nil {
+				return window, fmt.Errorf("invalid quiet hours '%s': %w", spec, err)
+			}
+			last := first
+			if isRange {
+				if last, err = parseWeekday(to); err != nil {
+					return window, fmt.Errorf("invalid quiet hours '%s': %w", spec, err)
+				}
+			}
+			for d := first; ; d = (d + 1) % 7 {
+				window.days[d] = true
+				if d == last {
+					break
+				}
+			}
+		}
+	}
+
+	from, to, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return window, fmt.Errorf("invalid quiet hours '%s' - expected [DAYS] HH:MM-HH:MM", spec)
+	}
+	var err error
+	if window.start, err = parseTimeOfDay(from); err != nil {
+		return window, fmt.Errorf("invalid quiet hours '%s': %w", spec, err)
+	}
+	if window.end, err = parseTimeOfDay(to); err != nil {
+		return window, fmt.Errorf("invalid quiet hours '%s': %w", spec, err)
+	}
+	return window, nil
+}
+
+func parseWeekday(name string) (int, error) {
+	for i, day := range weekdays {
+		if strings.EqualFold(name, day) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday '%s' - expected one of %s", name, strings.Join(weekdays, ", "))
+}
+
+/* Returns the minutes since midnight. 24:00 is allowed to end a window at midnight */
+func parseTimeOfDay(s string) (int, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(s, "%d:%d", &hours, &minutes); err != nil || hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time of day '%s' - expected HH:MM", s)
+	}
+	return hours*60 + minutes, nil
+}
+
+// active reports whether t is within one of the quiet windows
+func (q *quietHours) active(t time.Time) bool {
+	t = t.In(q.location)
+	day := int(t.Weekday())
+	yesterday := (day + 6) % 7
+	minute := t.Hour()*60 + t.Minute()
+
+	for _, w := range q.windows {
+		if w.start < w.end {
+			if w.days[day] && minute >= w.start && minute < w.end {
+				return true
+			}
+		} else if (w.days[day] && minute >= w.start) || (w.days[yesterday] && minute < w.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// suppresses reports whether a notification of the given priority must not be sent right now.
+// Nothing is suppressed when no quiet hours are configured
+func (q *quietHours) suppresses(priority int) bool {
+	return q != nil && priority < q.minPriority && q.active(time.Now())
+}
+
+// run sends the held notifications once the quiet hours end and the bridge is not paused,
+// until the bridge exits
+func (q *quietHours) run(svr *bridge) {
+	slog.Info("Quiet hours configured", "windows", len(q.windows), "timezone", q.location, "min_priority", q.minPriority, "queue", q.queue)
+
+	for range time.Tick(time.Minute) {
+		if q.active(time.Now()) || svr.pause.active() {
+			continue
+		}
+
+		if held := q.held.release(); len(held) > 0 {
+			slog.Info("Quiet hours ended - sending held notifications", "count", len(held))
+			sendHeld(held)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQuietWindow(t *testing.T) {
+	all := [7]bool{true, true, true, true, true, true, true}
+
+	tests := []struct {
+		spec    string
+		want    quietWindow
+		wantErr bool
+	}{
+		{spec: "22:00-07:00", want: quietWindow{days: all, start: 22 * 60, end: 7 * 60}},
+		{spec: "00:00-24:00", want: quietWindow{days: all, start: 0, end: 24 * 60}},
+		{spec: "Sat,Sun 00:00-24:00", want: quietWindow{days: [7]bool{true, false, false, false, false, false, true}, end: 24 * 60}},
+		{spec: "Mon-Fri 22:30-06:30", want: quietWindow{days: [7]bool{false, true, true, true, true, true, false}, start: 22*60 + 30, end: 6*60 + 30}},
+		{spec: "fri-mon 12:00-13:00", want: quietWindow{days: [7]bool{true, true, false, false, false, true, true}, start: 12 * 60, end: 13 * 60}},
+		{spec: "", wantErr: true},
+		{spec: "Mon Tue 22:00-07:00", wantErr: true},
+		{spec: "Someday 22:00-07:00", wantErr: true},
+		{spec: "Mon-Funday 22:00-07:00", wantErr: true},
+		{spec: "22:00", wantErr: true},
+		{spec: "22:00-24:01", wantErr: true},
+		{spec: "22:60-07:00", wantErr: true},
+		{spec: "ten-07:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseQuietWindow(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQuietWindow(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseQuietWindow(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietHoursActive(t *testing.T) {
+	q, err := parseQuietHours([]string{"Mon-Fri 22:00-07:00", "Sat,Sun 00:00-24:00"}, "UTC", 8, "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		time string
+		want bool
+	}{
+		{time: "2024-01-01T12:00:00Z", want: false}, // Monday noon
+		{time: "2024-01-01T22:00:00Z", want: true},  // Monday night
+		{time: "2024-01-02T06:59:00Z", want: true},  // Tuesday morning, after Monday night
+		{time: "2024-01-02T07:00:00Z", want: false}, // Tuesday, window over
+		{time: "2024-01-06T12:00:00Z", want: true},  // Saturday
+		{time: "2024-01-01T06:00:00Z", want: false}, // Monday morning, Sunday night is not a Mon-Fri window
+	}
+
+	for _, tt := range tests {
+		at, err := time.Parse(time.RFC3339, tt.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := q.active(at); got != tt.want {
+			t.Errorf("active(%s) = %v, want %v", tt.time, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* Clients without requests for this long are forgotten */
+const rateLimitIdle = 10 * time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the webhook calls of each client address with a token bucket refilled
+// at rate tokens per second and holding up to burst tokens
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), clients: map[string]*tokenBucket{}, lastSweep: time.Now()}
+}
+
+// allow takes a token from the bucket of a client. When none is left, it returns false along
+// with the time until the next token is available. All requests are allowed when no rate
+// limit is configured
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > rateLimitIdle {
+		for key, bucket := range l.clients {
+			if now.Sub(bucket.last) > rateLimitIdle {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.clients[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.clients[client] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// clientAddress is the IP address a request came from, without the port
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// throttle answers requests of clients exceeding the rate limit with 429 and returns true
+// when it did so
+func (svr *bridge) throttle(w http.ResponseWriter, r *http.Request) bool {
+	client := clientAddress(r)
+	ok, wait := svr.rateLimiter.allow(client)
+	if ok {
+		return false
+	}
+
+	contextLogger(r.Context()).Warn("Rate limit exceeded - rejecting request", "client", client)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+	metrics.Inc("requests_throttled")
+	return true
+}
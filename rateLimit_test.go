@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func sendFrom(handler http.HandlerFunc, client string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/gotify_webhook", nil)
+	r.RemoteAddr = client + ":41234"
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestRateLimitBurst(t *testing.T) {
+	svr := &bridge{rateLimiter: newRateLimiter(20, 3)}
+	handled := 0
+	handler := svr.inputHandler(func(w http.ResponseWriter, r *http.Request) { handled++ })
+
+	for i := 1; i <= 3; i++ {
+		if w := sendFrom(handler, "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d of the burst answered %d", i, w.Code)
+		}
+	}
+	w := sendFrom(handler, "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request past the burst answered %d, want 429", w.Code)
+	}
+	if retry := w.Header().Get("Retry-After"); retry != "1" {
+		t.Errorf("Retry-After = %q, want 1", retry)
+	}
+	if handled != 3 {
+		t.Errorf("%d requests handled, want 3", handled)
+	}
+
+	if w := sendFrom(handler, "10.0.0.2"); w.Code != http.StatusOK {
+		t.Errorf("another client answered %d while the first was throttled", w.Code)
+	}
+
+	/* 20 tokens per second bring one back after 50ms */
+	time.Sleep(60 * time.Millisecond)
+	if w := sendFrom(handler, "10.0.0.1"); w.Code != http.StatusOK {
+		t.Errorf("request after the refill answered %d", w.Code)
+	}
+	if w := sendFrom(handler, "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("second request after refilling a single token answered %d", w.Code)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	l := newRateLimiter(0.5, 0)
+	if ok, _ := l.allow("client"); !ok {
+		t.Fatal("first request rejected with a burst below 1")
+	}
+	ok, wait := l.allow("client")
+	if ok || wait <= time.Second || wait > 2*time.Second {
+		t.Errorf("allow() = %v, %s, want a wait of up to 2s", ok, wait)
+	}
+
+	var disabled *rateLimiter
+	for i := 0; i < 100; i++ {
+		if ok, _ := disabled.allow("client"); !ok {
+			t.Fatal("request rejected without rate limit")
+		}
+	}
+}
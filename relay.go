@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const relayPath = "/api/v2/alerts"
+
+// postableAlert is an alert as Prometheus posts it to the alerts API of Alertmanager
+type postableAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// relayedAlert is the state of an alert received through the alerts API. pending is set while
+// the current status still has to be sent to gotify
+type relayedAlert struct {
+	alert    postableAlert
+	status   string
+	notified bool
+	pending  bool
+}
+
+// alertRelay stands in for Alertmanager in small setups. Prometheus sends its alerts again on
+// every evaluation, so alerts are only sent to gotify when they start firing or resolve, and
+// alerts arriving within groupWait are sent together grouped by their alertname
+type alertRelay struct {
+	groupWait      time.Duration
+	resolveTimeout time.Duration
+
+	mu     sync.Mutex
+	alerts map[string]*relayedAlert
+}
+
+func newAlertRelay(groupWait time.Duration, resolveTimeout time.Duration) *alertRelay {
+	return &alertRelay{
+		groupWait:      groupWait,
+		resolveTimeout: resolveTimeout,
+		alerts:         map[string]*relayedAlert{},
+	}
+}
+
+// handleAlerts implements POST /api/v2/alerts of Alertmanager
+func (svr *bridge) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	log := contextLogger(r.Context())
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	if *maxRequestBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, *maxRequestBytes)
+	}
+	body, err := decodeBody(r, body, *maxRequestBytes)
+	if err == nil {
+		var alerts []postableAlert
+		if err = json.NewDecoder(body).Decode(&alerts); err == nil {
+			err = svr.relay.receive(alerts, time.Now())
+		}
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		rejectTooLarge(log, w, r, tooLarge)
+		return
+	}
+	if err != nil {
+		log.Warn("Invalid alerts posted", "error", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		metrics.Inc("requests_invalid")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// receive records the posted alerts. Alerts without an end time resolve once they weren't
+// posted again for resolveTimeout, just like in Alertmanager
+func (rl *alertRelay) receive(alerts []postableAlert, now time.Time) error {
+	for i, a := range alerts {
+		if len(a.Labels) == 0 {
+			return fmt.Errorf("alert %d has no labels", i)
+		}
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for _, a := range alerts {
+		if a.StartsAt.IsZero() {
+			a.StartsAt = now
+		}
+		if a.EndsAt.IsZero() {
+			a.EndsAt = now.Add(rl.resolveTimeout)
+		}
+
+		fingerprint := relayFingerprint(a.Labels)
+		known := rl.alerts[fingerprint]
+		if !a.EndsAt.After(now) {
+			switch {
+			case known == nil || known.status == "resolved":
+			case known.notified:
+				a.StartsAt = known.alert.StartsAt
+				if len(a.Annotations) == 0 {
+					a.Annotations, a.GeneratorURL = known.alert.Annotations, known.alert.GeneratorURL
+				}
+				known.alert, known.status, known.pending = a, "resolved", true
+			default:
+				/* Resolved before it was sent - nobody needs to hear about it */
+				delete(rl.alerts, fingerprint)
+			}
+			continue
+		}
+
+		if known == nil || known.status == "resolved" {
+			rl.alerts[fingerprint] = &relayedAlert{alert: a, status: "firing", pending: true}
+			continue
+		}
+		/* Still firing - keep the original start so the alert isn't sent again */
+		a.StartsAt = known.alert.StartsAt
+		known.alert = a
+	}
+	return nil
+}
+
+// due resolves alerts that weren't posted again in time and returns the pending alerts grouped
+// by their alertname, marking them as sent
+func (rl *alertRelay) due(now time.Time) map[string][]Alert {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	groups := map[string][]Alert{}
+	for fingerprint, known := range rl.alerts {
+		if known.status == "firing" && !known.alert.EndsAt.After(now) {
+			known.status, known.pending = "resolved", known.notified
+			if !known.notified {
+				delete(rl.alerts, fingerprint)
+				continue
+			}
+		}
+		if !known.pending {
+			continue
+		}
+
+		name := known.alert.Labels[model.AlertNameLabel]
+		groups[name] = append(groups[name], known.toAlert(fingerprint))
+		known.pending = false
+		known.notified = true
+		if known.status == "resolved" {
+			delete(rl.alerts, fingerprint)
+		}
+	}
+	return groups
+}
+
+func (known *relayedAlert) toAlert(fingerprint string) Alert {
+	alert := Alert{
+		Status:       known.status,
+		Labels:       known.alert.Labels,
+		Annotations:  known.alert.Annotations,
+		GeneratorURL: known.alert.GeneratorURL,
+		StartsAt:     known.alert.StartsAt.Format(time.RFC3339Nano),
+		Fingerprint:  fingerprint,
+	}
+	if alert.Annotations == nil {
+		alert.Annotations = map[string]string{}
+	}
+	if known.status == "resolved" {
+		alert.EndsAt = known.alert.EndsAt.Format(time.RFC3339Nano)
+	}
+	return alert
+}
+
+// run sends the pending alerts to gotify every groupWait until the bridge exits
+func (rl *alertRelay) run(svr *bridge) {
+	slog.Info("Relaying alerts posted to the alerts API", "path", relayPath, "group_wait", rl.groupWait)
+	for now := range time.Tick(rl.groupWait) {
+		rl.send(svr, now)
+	}
+}
+
+// send processes the pending alerts like webhook calls of Alertmanager, one per alertname.
+// Alerts gotify did not accept are not sent by the relay again, but kept for replay
+func (rl *alertRelay) send(svr *bridge, now time.Time) {
+	groups := rl.due(now)
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		notification := relayNotification(name, groups[name])
+		body, _ := json.Marshal(notification)
+		code, text := svr.processNotification(context.Background(), svr.gotifyToken.Get(), notification, body)
+		if code != http.StatusOK {
+			slog.Warn("Unable to send relayed alerts to gotify", "alertname", name, "status", code, "response", text)
+		}
+	}
+}
+
+// relayNotification builds the webhook call Alertmanager would send for a group of alerts
+func relayNotification(name string, alerts []Alert) Notification {
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Fingerprint < alerts[j].Fingerprint })
+
+	status := "resolved"
+	for _, alert := range alerts {
+		if alert.Status == "firing" {
+			status = "firing"
+		}
+	}
+
+	return Notification{
+		Version:           "4",
+		GroupKey:          fmt.Sprintf("{}:{alertname=%q}", name),
+		Status:            status,
+		Receiver:          "relay",
+		GroupLabels:       map[string]string{model.AlertNameLabel: name},
+		CommonLabels:      commonEntries(alerts, func(a Alert) map[string]string { return a.Labels }),
+		CommonAnnotations: commonEntries(alerts, func(a Alert) map[string]string { return a.Annotations }),
+		Alerts:            alerts,
+	}
+}
+
+/* The entries all alerts have in common, as in CommonLabels of Alertmanager */
+func commonEntries(alerts []Alert, entries func(Alert) map[string]string) map[string]string {
+	common := map[string]string{}
+	for key, value := range entries(alerts[0]) {
+		common[key] = value
+	}
+	for _, alert := range alerts[1:] {
+		other := entries(alert)
+		for key, value := range common {
+			if other[key] != value {
+				delete(common, key)
+			}
+		}
+	}
+	return common
+}
+
+func relayFingerprint(labels map[string]string) string {
+	set := make(model.LabelSet, len(labels))
+	for name, value := range labels {
+		set[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return set.Fingerprint().String()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRelayReceive(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	rl := newAlertRelay(30*time.Second, 5*time.Minute)
+	disk := postableAlert{Labels: map[string]string{"alertname": "Disk", "instance": "web1"}}
+	cpu := postableAlert{Labels: map[string]string{"alertname": "CPU", "instance": "web1"}}
+
+	if err := rl.receive([]postableAlert{disk, {}}, start); err == nil {
+		t.Fatal("receive() accepted an alert without labels")
+	}
+
+	rl.receive([]postableAlert{disk, cpu}, start)
+	due := rl.due(start.Add(30 * time.Second))
+	if len(due["Disk"]) != 1 || len(due["CPU"]) != 1 || due["Disk"][0].Status != "firing" {
+		t.Fatalf("due() = %+v, want both alerts firing", due)
+	}
+
+	/* Prometheus sends firing alerts again on every evaluation */
+	rl.receive([]postableAlert{disk, cpu}, start.Add(time.Minute))
+	if due := rl.due(start.Add(90 * time.Second)); len(due) != 0 {
+		t.Errorf("alerts sent again while still firing: %+v", due)
+	}
+
+	resolved := disk
+	resolved.EndsAt = start.Add(2 * time.Minute)
+	rl.receive([]postableAlert{resolved, cpu}, start.Add(2*time.Minute))
+	due = rl.due(start.Add(150 * time.Second))
+	if len(due) != 1 || len(due["Disk"]) != 1 || due["Disk"][0].Status != "resolved" || due["Disk"][0].StartsAt != start.Format(time.RFC3339Nano) {
+		t.Errorf("due() after resolving = %+v, want Disk resolved with its original start", due)
+	}
+
+	/* CPU was last posted at 2m, so it resolves after the resolve timeout */
+	if due := rl.due(start.Add(6 * time.Minute)); len(due) != 0 {
+		t.Errorf("alert resolved before the resolve timeout: %+v", due)
+	}
+	due = rl.due(start.Add(8 * time.Minute))
+	if len(due["CPU"]) != 1 || due["CPU"][0].Status != "resolved" {
+		t.Errorf("due() after the resolve timeout = %+v, want CPU resolved", due)
+	}
+	if len(rl.alerts) != 0 {
+		t.Errorf("resolved alerts still remembered: %+v", rl.alerts)
+	}
+}
+
+func TestRelayResolvedBeforeSent(t *testing.T) {
+	now := time.Now()
+	rl := newAlertRelay(30*time.Second, 5*time.Minute)
+	alert := postableAlert{Labels: map[string]string{"alertname": "Flaky"}}
+	rl.receive([]postableAlert{alert}, now)
+
+	alert.EndsAt = now.Add(time.Second)
+	rl.receive([]postableAlert{alert}, now.Add(time.Second))
+	if due := rl.due(now.Add(30 * time.Second)); len(due) != 0 {
+		t.Errorf("alert resolved within the group wait was sent: %+v", due)
+	}
+}
+
+func TestRelayNotification(t *testing.T) {
+	alerts := []Alert{
+		{Fingerprint: "b", Status: "resolved", Labels: map[string]string{"alertname": "Disk", "instance": "web2", "team": "ops"}},
+		{Fingerprint: "a", Status: "firing", Labels: map[string]string{"alertname": "Disk", "instance": "web1", "team": "ops"}},
+	}
+	n := relayNotification("Disk", alerts)
+	if n.Status != "firing" || n.Alerts[0].Fingerprint != "a" || n.GroupKey != `{}:{alertname="Disk"}` {
+		t.Errorf("relayNotification() = %+v", n)
+	}
+	if want := map[string]string{"alertname": "Disk", "team": "ops"}; !reflect.DeepEqual(n.CommonLabels, want) {
+		t.Errorf("CommonLabels = %v, want %v", n.CommonLabels, want)
+	}
+}
+
+/* Relayed alerts gotify did not accept wait for replay instead of being posted again */
+func TestRelayFailingGotify(t *testing.T) {
+	gotify := newFakeGotify(t, 503, 0)
+	svr := newWebhookTestBridge(t, gotify)
+	svr.relay = newAlertRelay(time.Second, 5*time.Minute)
+
+	now := time.Now()
+	svr.relay.receive([]postableAlert{{Labels: map[string]string{"alertname": "Disk"}, Annotations: map[string]string{"summary": "Disk full", "description": "Disk full on web1"}}}, now)
+	svr.relay.send(svr, now.Add(time.Second))
+	if got := gotify.received(); len(got) != 1 || got[0] != "default-token" {
+		t.Fatalf("gotify received %v, want a single attempt with the default token", got)
+	}
+	if svr.replay.count() != 1 {
+		t.Fatalf("%d notifications kept for replay, want 1", svr.replay.count())
+	}
+
+	svr.relay.receive([]postableAlert{{Labels: map[string]string{"alertname": "Disk"}}}, now.Add(2*time.Second))
+	svr.relay.send(svr, now.Add(3*time.Second))
+	if got := gotify.received(); len(got) != 1 {
+		t.Errorf("relay posted the failed alert again: %v", got)
+	}
+
+	gotify.answer(200)
+	if sent := svr.replay.replay(); sent != 1 {
+		t.Errorf("replay() = %d, want the relayed alert sent", sent)
+	}
+	if got := gotify.received(); len(got) != 2 {
+		t.Errorf("gotify received %v after replaying", got)
+	}
+}
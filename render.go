@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// renderAlert turns a single alert into the notification sent to gotify. When the alert can
+// not be rendered, proceed is false and err holds the reason - unless --dispatch_errors is
+// set, in which case the returned notification describes the error and proceed stays true
+func (svr *bridge) renderAlert(logger *slog.Logger, alert Alert, token string, body []byte) (outbound GotifyNotification, proceed bool, err error) {
+	var externalURL *url.URL
+	var defaultTitle bool
+	var defaultMsg bool
+	var renderErr error
+	var header string
+	var detailsFooter string
+	var footers []string
+	extras := make(map[string]interface{})
+	proceed = true
+	title := ""
+	message := ""
+	priority := *svr.defaultPriority
+	tmpls := svr.userTemplates.Get()
+
+	fail := func(failure error) {
+		proceed = false
+		renderErr = failure
+		logger.Debug("Rendering alert failed", "error", failure)
+		if *svr.dispatchErrors {
+			proceed = true
+			title = "Alertmanager-Gotify-Bridge Error"
+			message = fmt.Sprintf("    Error: %s\n\nAlso check Alertmanager, maybe an alert was raised!\n\nIcomming request:\n%s", failure.Error(), body)
+		}
+	}
+
+	if alert.ExternalURL != "" {
+		externalURL, err = url.Parse(alert.ExternalURL)
+		if err != nil {
+			logger.Warn("External URL format error", "error", err)
+		}
+	}
+
+	if *markdown || *svr.extendedDetails || *markdownDetails {
+		// set text to markdown
+		extrasContentType := make(map[string]string)
+		extrasContentType["contentType"] = "text/markdown"
+		extras["client::display"] = extrasContentType
+	}
+
+	if *svr.extendedDetails {
+		statusTitle, statusHeader, footer, err := svr.extendedDetailsLayout(alert)
+		if err != nil {
+			fail(err)
+		} else {
+			header, detailsFooter = statusHeader, footer
+			title += statusTitle
+			message += header
+		}
+	}
+
+	/* Values interpolated into Markdown messages could otherwise inject HTML */
+	renderMessage := renderTemplate
+	if _, isMarkdown := extras["client::display"]; isMarkdown && *escapeHTML {
+		renderMessage = renderEscapedTemplate
+	}
+
+	// Checks if user defined templates exist
+	if tmpls != nil {
+		// Executes a user title template if one exists
+		userTitleTmpl, err := executeUserTemplate(alert, fmt.Sprintf("title=%s", token), tmpls)
+		if err != nil {
+			logger.Debug("Falling back to default alerting", "error", err)
+			defaultTitle = true
+		} else {
+			tmplTitle, err := renderTemplate(userTitleTmpl, alert, externalURL)
+			if err != nil {
+				fail(err)
+			} else {
+				title += tmplTitle
+			}
+
+			logger.Debug("Rendered user-defined title template", "title", title)
+		}
+
+		// Executes a user message template if one exists
+		userMsgTmpl, err := executeUserTemplate(alert, token, tmpls)
+		if err != nil {
+			logger.Debug("Falling back to default alerting", "error", err)
+			defaultMsg = true
+		} else {
+			tmplMsg, err := renderMessage(userMsgTmpl, alert, externalURL)
+			if err != nil {
+				fail(err)
+			} else {
+				message += tmplMsg
+			}
+
+			logger.Debug("Rendered user-defined message template", "message", message)
+		}
+	} else {
+		defaultTitle = true
+		defaultMsg = true
+	}
+
+	if defaultTitle {
+		if val, ok := svr.titleTemplate(alert); ok {
+			templatedTitle, err := renderTemplate(val, alert, externalURL)
+			if err != nil {
+				fail(err)
+			} else {
+				title += templatedTitle
+			}
+
+			logger.Debug("Rendered title", "title", title)
+		} else {
+			fail(fmt.Errorf("Missing annotation: %s", *svr.titleAnnotation))
+		}
+	}
+
+	if defaultMsg {
+		if val, ok := svr.messageTemplate(alert); ok {
+			templatedMsg, err := renderMessage(val, alert, externalURL)
+			if err != nil {
+				fail(err)
+			} else {
+				message += templatedMsg
+			}
+
+			logger.Debug("Rendered message", "message", message)
+		} else {
+			fail(fmt.Errorf("Missing annotation: %s", *svr.messageAnnotation))
+		}
+	}
+
+	if resolved, err := svr.resolvePriority(logger, alert); err != nil {
+		fail(err)
+	} else {
+		priority = resolved
+	}
+
+	if alert.Status == "resolved" && *svr.resolvedPriority >= 0 {
+		priority = *svr.resolvedPriority
+		logger.Debug("Alert resolved - using resolved priority", "priority", priority)
+	}
+
+	if *svr.extendedDetails {
+		if detailsFooter != "" {
+			message += detailsFooter
+			footers = append(footers, detailsFooter)
+		}
+		if strings.HasPrefix(alert.GeneratorURL, "http") {
+			extrasNotification := make(map[string]map[string]string)
+			extrasNotification["click"] = make(map[string]string)
+			extrasNotification["click"]["url"] = alert.GeneratorURL
+			extras["client::notification"] = extrasNotification
+		}
+	}
+
+	if *markdownDetails {
+		statusLine := ""
+		if !*svr.extendedDetails {
+			statusLine = svr.statusLine(alert)
+		}
+		message = formatMarkdownDetails(alert, message, statusLine, !*svr.extendedDetails)
+		if !*svr.extendedDetails {
+			if statusLine != "" {
+				header = statusLine + "\n\n"
+			}
+			if strings.HasPrefix(alert.GeneratorURL, "http") {
+				footers = append(footers, "\n\n[Go to source]("+alert.GeneratorURL+")")
+			}
+		}
+	}
+
+	if len(svr.includeDetails) > 0 {
+		_, asMarkdown := extras["client::display"]
+		if details := svr.includedDetails(alert, asMarkdown); details != "" {
+			message += "\n\n" + details
+		}
+	}
+
+	if *clickToGenerator {
+		// sets the notification to be clickable without the need to use
+		// extendedDetails, mainly this is to work with the markdown formatting
+		// so there is no need to add HTML to the notification, and not disturb
+		// the existing flags.
+		if alert.GeneratorURL != "" && strings.HasPrefix(alert.GeneratorURL, "http") {
+			extrasNotification := make(map[string]map[string]string)
+			extrasNotification["click"] = make(map[string]string)
+			extrasNotification["click"]["url"] = alert.GeneratorURL
+			extras["client::notification"] = extrasNotification
+		}
+	}
+
+	if val, ok := alert.Annotations[*runbookAnnotation]; ok && *runbookAnnotation != "" {
+		runbookURL, err := renderTemplate(val, alert, externalURL)
+		if err != nil {
+			fail(err)
+		} else if runbookURL = strings.TrimSpace(runbookURL); runbookURL != "" {
+			// the runbook is more useful than the generator when tapping the notification,
+			// so it takes precedence over --click_to_generator and --extended_details
+			link := "\n\nRunbook: " + runbookURL
+			if _, ok := extras["client::display"]; ok {
+				link = "\n\n[Runbook](" + runbookURL + ")"
+			}
+			message += link
+			footers = append(footers, link)
+			extras["client::notification"] = map[string]map[string]string{
+				"click": {"url": runbookURL},
+			}
+			logger.Debug("Added runbook link", "url", runbookURL)
+		}
+	}
+
+	if *silenceLink != "off" && alert.Status == "firing" {
+		if silenceURL := alert.SilenceURL(); silenceURL != "" {
+			link := "\n\nSilence: " + silenceURL
+			if _, ok := extras["client::display"]; ok {
+				link = "\n\n[Silence](" + silenceURL + ")"
+			}
+			message += link
+			footers = append(footers, link)
+			if *silenceLink == "click" {
+				extras["client::notification"] = map[string]map[string]string{
+					"click": {"url": silenceURL},
+				}
+			}
+			logger.Debug("Added silence link", "url", silenceURL)
+		}
+	}
+
+	if customExtras, err := annotationExtras(alert, externalURL); err != nil {
+		fail(err)
+	} else if len(customExtras) > 0 {
+		if extras, err = normalizeExtras(extras); err != nil {
+			fail(err)
+		} else {
+			mergeExtras(extras, customExtras)
+			logger.Debug("Merged extras from annotations", "extras", customExtras)
+		}
+	}
+
+	var truncated bool
+	if message, truncated = truncateMessage(message, *maxMessageLength, header, footers); truncated {
+		logger.Debug("Message truncated", "max_message_length", *maxMessageLength)
+		metrics.Inc("messages_truncated")
+	}
+
+	outbound = GotifyNotification{
+		Title:    title,
+		Message:  message,
+		Priority: priority,
+		Extras:   extras,
+	}
+	return outbound, proceed, renderErr
+}
+
+// resolvePriority looks up the priority of an alert from the priority template, the priority
+// annotation, the priority label and the severity label, in that order, before falling back to
+// the default.
+// Values of the annotation and label may be numbers or severities from --severity_priority.
+// With --strict_priority, values that are neither or out of range are an error
+func (svr *bridge) resolvePriority(logger *slog.Logger, alert Alert) (int, error) {
+	if *svr.priorityTemplate != "" {
+		priority, ok, err := svr.templatedPriority(logger, alert)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return svr.clampPriority(logger, "template", priority)
+		}
+	}
+
+	lookups := []struct {
+		source string
+		values map[string]string
+		key    string
+	}{
+		{"annotation", alert.Annotations, *svr.priorityAnnotation},
+		{"label", alert.Labels, *svr.priorityLabel},
+		{"severity", alert.Labels, *svr.severityLabel},
+	}
+
+	for _, lookup := range lookups {
+		val, ok := lookup.values[lookup.key]
+		if !ok || lookup.key == "" {
+			continue
+		}
+		if lookup.source != "severity" {
+			if tmp, err := strconv.Atoi(val); err == nil {
+				logger.Debug("Priority found", "source", lookup.source, "key", lookup.key, "priority", tmp)
+				return svr.clampPriority(logger, lookup.source, tmp)
+			}
+		}
+		if mapped, ok := svr.severityPriorities[val]; ok {
+			logger.Debug("Priority found in severity map", "source", lookup.source, "key", lookup.key, "severity", val, "priority", mapped)
+			return svr.clampPriority(logger, lookup.source, mapped)
+		}
+		if lookup.source != "severity" && *svr.strictPriority {
+			return 0, fmt.Errorf("invalid priority '%s' in %s %s", val, lookup.source, lookup.key)
+		}
+	}
+
+	logger.Debug("No priority found - Falling back to default", "priority", *svr.defaultPriority)
+	return *svr.defaultPriority, nil
+}
+
+// clampPriority moves a priority taken from an alert into the range given by --min_priority
+// and --max_priority, or reports it as an error with --strict_priority
+func (svr *bridge) clampPriority(logger *slog.Logger, source string, priority int) (int, error) {
+	if priority >= *svr.minPriority && priority <= *svr.maxPriority {
+		return priority, nil
+	}
+	if *svr.strictPriority {
+		return 0, fmt.Errorf("priority %d of the %s is out of range %d to %d", priority, source, *svr.minPriority, *svr.maxPriority)
+	}
+	clamped := *svr.minPriority
+	if priority > *svr.maxPriority {
+		clamped = *svr.maxPriority
+	}
+	logger.Debug("Priority out of range - clamping it", "source", source, "priority", priority, "clamped", clamped)
+	return clamped, nil
+}
+
+// templatedPriority evaluates --priority_template for an alert and clamps the result into the
+// range given by --priority_template_min and --priority_template_max. An empty result means the
+// template does not decide the priority of this alert, as does a result that isn't a number
+// unless --strict_priority is set
+func (svr *bridge) templatedPriority(logger *slog.Logger, alert Alert) (int, bool, error) {
+	rendered, err := renderTemplate(*svr.priorityTemplate, alert, nil)
+	if err != nil {
+		if *svr.strictPriority {
+			return 0, false, fmt.Errorf("unable to render priority template: %w", err)
+		}
+		logger.Warn("Unable to render priority template", "error", err)
+		return 0, false, nil
+	}
+
+	rendered = strings.TrimSpace(rendered)
+	if rendered == "" {
+		logger.Debug("Priority template rendered empty - Ignoring it")
+		return 0, false, nil
+	}
+
+	priority, err := strconv.Atoi(rendered)
+	if err != nil {
+		if *svr.strictPriority {
+			return 0, false, fmt.Errorf("priority template rendered '%s', which is not a number", rendered)
+		}
+		logger.Warn("Priority template did not render a number", "result", rendered)
+		return 0, false, nil
+	}
+
+	if priority < *svr.priorityTemplateMin {
+		priority = *svr.priorityTemplateMin
+	} else if priority > *svr.priorityTemplateMax {
+		priority = *svr.priorityTemplateMax
+	}
+	logger.Debug("Priority found in priority template", "priority", priority)
+	return priority, true, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+)
+
+/* Flags of the bridge that decide the priority of an alert, with their defaults */
+type priorityTestFlags struct {
+	template           string
+	templateMin        int
+	templateMax        int
+	annotation         string
+	label              string
+	severity           string
+	defaultPriority    int
+	minPriority        int
+	maxPriority        int
+	strict             bool
+	severityPriorities map[string]int
+}
+
+func (f priorityTestFlags) bridge() *bridge {
+	if f.annotation == "" {
+		f.annotation = "priority"
+	}
+	if f.severity == "" {
+		f.severity = "severity"
+	}
+	if f.templateMax == 0 {
+		f.templateMax = 10
+	}
+	if f.maxPriority == 0 {
+		f.maxPriority = 10
+	}
+	if f.defaultPriority == 0 {
+		f.defaultPriority = 5
+	}
+	return &bridge{
+		priorityTemplate:    &f.template,
+		priorityTemplateMin: &f.templateMin,
+		priorityTemplateMax: &f.templateMax,
+		priorityAnnotation:  &f.annotation,
+		priorityLabel:       &f.label,
+		severityLabel:       &f.severity,
+		defaultPriority:     &f.defaultPriority,
+		minPriority:         &f.minPriority,
+		maxPriority:         &f.maxPriority,
+		strictPriority:      &f.strict,
+		severityPriorities:  f.severityPriorities,
+	}
+}
+
+func TestClampPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    priorityTestFlags
+		priority int
+		want     int
+		wantErr  bool
+	}{
+		{name: "in range", priority: 7, want: 7},
+		{name: "at bounds", flags: priorityTestFlags{minPriority: 2, maxPriority: 8}, priority: 8, want: 8},
+		{name: "below minimum", flags: priorityTestFlags{minPriority: 2, maxPriority: 8}, priority: 1, want: 2},
+		{name: "above maximum", flags: priorityTestFlags{minPriority: 2, maxPriority: 8}, priority: 15, want: 8},
+		{name: "negative", priority: -3, want: 0},
+		{name: "strict out of range", flags: priorityTestFlags{maxPriority: 8, strict: true}, priority: 9, wantErr: true},
+		{name: "strict in range", flags: priorityTestFlags{maxPriority: 8, strict: true}, priority: 8, want: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.flags.bridge().clampPriority(slog.Default(), "annotation", tt.priority)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clampPriority(%d) error = %v, wantErr %v", tt.priority, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("clampPriority(%d) = %d, want %d", tt.priority, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePriority(t *testing.T) {
+	severities := map[string]int{"critical": 9, "warning": 6, "info": 2}
+
+	tests := []struct {
+		name        string
+		flags       priorityTestFlags
+		annotations map[string]string
+		labels      map[string]string
+		want        int
+		wantErr     bool
+	}{
+		{name: "default", want: 5},
+		{name: "annotation", annotations: map[string]string{"priority": "7"}, want: 7},
+		{name: "annotation severity", flags: priorityTestFlags{severityPriorities: severities}, annotations: map[string]string{"priority": "warning"}, want: 6},
+		{name: "annotation before label", flags: priorityTestFlags{label: "prio"}, annotations: map[string]string{"priority": "7"}, labels: map[string]string{"prio": "3"}, want: 7},
+		{name: "label", flags: priorityTestFlags{label: "prio"}, labels: map[string]string{"prio": "3"}, want: 3},
+		{name: "label not looked up without flag", labels: map[string]string{"": "3"}, want: 5},
+		{name: "severity label", flags: priorityTestFlags{severityPriorities: severities}, labels: map[string]string{"severity": "critical"}, want: 9},
+		{name: "severity label number is not a priority", labels: map[string]string{"severity": "8"}, want: 5},
+		{name: "unknown severity", flags: priorityTestFlags{severityPriorities: severities}, labels: map[string]string{"severity": "page"}, want: 5},
+		{name: "invalid annotation falls through", flags: priorityTestFlags{severityPriorities: severities}, annotations: map[string]string{"priority": "high"}, labels: map[string]string{"severity": "info"}, want: 2},
+		{name: "invalid annotation with strict", flags: priorityTestFlags{strict: true}, annotations: map[string]string{"priority": "high"}, wantErr: true},
+		{name: "unknown severity with strict", flags: priorityTestFlags{strict: true}, labels: map[string]string{"severity": "page"}, want: 5},
+		{name: "annotation clamped", flags: priorityTestFlags{maxPriority: 8}, annotations: map[string]string{"priority": "12"}, want: 8},
+		{name: "severity clamped", flags: priorityTestFlags{minPriority: 4, severityPriorities: severities}, labels: map[string]string{"severity": "info"}, want: 4},
+		{name: "template", flags: priorityTestFlags{template: `{{ if eq .Labels.env "prod" }}9{{ end }}`}, labels: map[string]string{"env": "prod"}, annotations: map[string]string{"priority": "3"}, want: 9},
+		{name: "empty template falls through", flags: priorityTestFlags{template: `{{ if eq .Labels.env "prod" }}9{{ end }}`}, labels: map[string]string{"env": "dev"}, annotations: map[string]string{"priority": "3"}, want: 3},
+		{name: "template within its range", flags: priorityTestFlags{template: "20", templateMin: 1, templateMax: 6}, want: 6},
+		{name: "template not a number", flags: priorityTestFlags{template: "high"}, annotations: map[string]string{"priority": "3"}, want: 3},
+		{name: "template not a number with strict", flags: priorityTestFlags{template: "high", strict: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := Alert{Status: "firing", Annotations: tt.annotations, Labels: tt.labels}
+			got, err := tt.flags.bridge().resolvePriority(slog.Default(), alert)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolvePriority() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolvePriority() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// reminder is a firing alert that is sent to gotify again until it resolves. The bridge is
+// part of the reminder since endpoints of --config_file share the renotifier
+type reminder struct {
+	svr      *bridge
+	alert    Alert
+	token    string
+	outbound GotifyNotification
+	due      time.Time
+	count    int
+	lastSeen time.Time
+}
+
+// renotifier sends firing alerts to gotify again every interval until a resolved notification
+// arrives, raising the priority by step each time up to maxPriority. Alerts Alertmanager did
+// not send again for forgetAfter are assumed resolved without a resolved notification
+type renotifier struct {
+	interval    time.Duration
+	step        int
+	maxPriority int
+	forgetAfter time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*reminder
+}
+
+func newRenotifier(interval time.Duration, step int, maxPriority int, forgetAfter time.Duration) *renotifier {
+	return &renotifier{
+		interval:    interval,
+		step:        step,
+		maxPriority: maxPriority,
+		forgetAfter: forgetAfter,
+		pending:     map[string]*reminder{},
+	}
+}
+
+// track starts sending reminders for a firing alert that was just dispatched, replacing any
+// earlier reminder for the same fingerprint. Nothing is tracked when re-notification is off
+func (r *renotifier) track(svr *bridge, alert Alert, token string, outbound GotifyNotification) {
+	if r == nil || alert.Fingerprint == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[alert.Fingerprint] = &reminder{
+		svr:      svr,
+		alert:    alert,
+		token:    token,
+		outbound: outbound,
+		due:      time.Now().Add(r.interval),
+		lastSeen: time.Now(),
+	}
+}
+
+// seen notes that Alertmanager still sends a firing alert, which keeps its reminders going
+func (r *renotifier) seen(fingerprint string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rem, ok := r.pending[fingerprint]; ok {
+		rem.lastSeen = time.Now()
+	}
+}
+
+// forget stops the reminders for an alert, e.g. because it resolved
+func (r *renotifier) forget(fingerprint string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, fingerprint)
+}
+
+/* Returns the reminders that are due and schedules their next run, dropping stale ones */
+func (r *renotifier) due() map[string]reminder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	due := map[string]reminder{}
+	for fingerprint, rem := range r.pending {
+		if now.Sub(rem.lastSeen) > r.forgetAfter {
+			slog.Debug("Alert was not sent again by Alertmanager - stopping its reminders", "fingerprint", fingerprint, "last_seen", rem.lastSeen)
+			delete(r.pending, fingerprint)
+			continue
+		}
+		if now.Before(rem.due) {
+			continue
+		}
+		rem.count++
+		rem.due = now.Add(r.interval)
+		due[fingerprint] = *rem
+	}
+	return due
+}
+
+// priority escalates the priority of a reminder by step for every reminder sent before,
+// without exceeding maxPriority
+func (r *renotifier) priority(rem reminder) int {
+	priority := rem.outbound.Priority
+	if r.step <= 0 || priority >= r.maxPriority {
+		return priority
+	}
+
+	priority += r.step * rem.count
+	if priority > r.maxPriority {
+		priority = r.maxPriority
+	}
+	return priority
+}
+
+// run sends the reminders that are due until the bridge exits
+func (r *renotifier) run() {
+	interval := r.interval / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	slog.Info("Re-notifying firing alerts", "interval", r.interval, "priority_step", r.step, "max_priority", r.maxPriority, "forget_after", r.forgetAfter)
+
+	for range time.Tick(interval) {
+		for fingerprint, rem := range r.due() {
+			r.send(fingerprint, rem)
+		}
+	}
+}
+
+func (r *renotifier) send(fingerprint string, rem reminder) {
+	svr := rem.svr
+	logger := slog.With("fingerprint", fingerprint, "reminder", rem.count)
+	outbound := rem.outbound
+	outbound.Priority = r.priority(rem)
+	if svr.pause.active() {
+		logger.Debug("Reminder skipped while paused")
+		return
+	}
+	if svr.quietHours.suppresses(outbound.Priority) {
+		logger.Debug("Reminder skipped for quiet hours", "priority", outbound.Priority)
+		return
+	}
+
+	if *svr.dryRun {
+		svr.dryRunResult(logger, "Reminder", outbound)
+		return
+	}
+
+	statusCode, status, messageID, err := svr.dispatch(context.Background(), logger, rem.token, outbound)
+	if err != nil || statusCode != 200 {
+		logger.Warn("Reminder processed", "outcome", "failed", "status", status, "error", err)
+		svr.countAlert("alerts_failed", rem.alert)
+		svr.history.add(rem.alert, outbound, "failed", statusCode, 0, err)
+		svr.dispatchHook.fire(rem.alert, outbound, "failed", statusCode, 0, err)
+		return
+	}
+	logger.Info("Reminder processed", "outcome", "dispatched", "message_id", messageID, "priority", outbound.Priority)
+	svr.countAlert("alerts_renotified", rem.alert)
+	svr.history.add(rem.alert, outbound, "renotified", statusCode, messageID, nil)
+	svr.dispatchHook.fire(rem.alert, outbound, "renotified", statusCode, messageID, nil)
+
+	/* With --on_resolve, the reminder takes the place of the message sent before */
+	if *svr.onResolve == "new" || messageID == 0 {
+		return
+	}
+	if previous, ok := svr.messages.Get(fingerprint); ok {
+		if err := svr.deleteMessage(context.Background(), previous.ID); err != nil {
+			logger.Warn("Error deleting message replaced by reminder", "message_id", previous.ID, "error", err)
+		}
+	}
+	err = svr.messages.Put(fingerprint, storedMessage{ID: messageID, Title: outbound.Title, Message: outbound.Message})
+	if err != nil {
+		logger.Warn("Unable to update message store", "error", err)
+	}
+}
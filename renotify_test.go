@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+/* Moves the schedule of a tracked alert back in time instead of waiting */
+func backdate(r *renotifier, fingerprint string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[fingerprint].due = r.pending[fingerprint].due.Add(-d)
+	r.pending[fingerprint].lastSeen = r.pending[fingerprint].lastSeen.Add(-d)
+}
+
+func TestRenotifierDue(t *testing.T) {
+	r := newRenotifier(time.Hour, 0, 10, 5*time.Hour)
+	r.track(nil, Alert{Fingerprint: "a1"}, "token", GotifyNotification{Priority: 5})
+	r.track(nil, Alert{}, "token", GotifyNotification{})
+
+	if due := r.due(); len(due) != 0 {
+		t.Fatalf("%d reminders due right after dispatching", len(due))
+	}
+
+	backdate(r, "a1", time.Hour)
+	due := r.due()
+	if rem, ok := due["a1"]; !ok || rem.count != 1 || rem.token != "token" {
+		t.Fatalf("due() = %+v, want the first reminder of a1", due)
+	}
+	if due := r.due(); len(due) != 0 {
+		t.Errorf("reminder due again before the next interval: %+v", due)
+	}
+
+	backdate(r, "a1", time.Hour)
+	if rem := r.due()["a1"]; rem.count != 2 {
+		t.Errorf("second reminder has count %d, want 2", rem.count)
+	}
+
+	r.forget("a1")
+	if len(r.pending) != 0 {
+		t.Errorf("reminders left after forget: %+v", r.pending)
+	}
+}
+
+func TestRenotifierForgetsUnseenAlerts(t *testing.T) {
+	r := newRenotifier(time.Hour, 0, 10, 3*time.Hour)
+	r.track(nil, Alert{Fingerprint: "silenced"}, "", GotifyNotification{})
+	r.track(nil, Alert{Fingerprint: "firing"}, "", GotifyNotification{})
+
+	backdate(r, "silenced", 2*time.Hour)
+	backdate(r, "firing", 2*time.Hour)
+	r.seen("firing")
+	if due := r.due(); len(due) != 2 {
+		t.Fatalf("due() = %+v, want both alerts within --renotify_forget_after", due)
+	}
+
+	backdate(r, "silenced", 2*time.Hour)
+	backdate(r, "firing", 2*time.Hour)
+	due := r.due()
+	if _, ok := due["silenced"]; ok {
+		t.Error("reminder sent for an alert Alertmanager stopped sending")
+	}
+	if _, ok := due["firing"]; !ok {
+		t.Error("no reminder for an alert Alertmanager still sends")
+	}
+	if _, ok := r.pending["silenced"]; ok {
+		t.Error("unseen alert still tracked")
+	}
+}
+
+func TestRenotifierPriority(t *testing.T) {
+	tests := []struct {
+		step, max, priority, count int
+		want                       int
+	}{
+		{step: 0, max: 10, priority: 5, count: 3, want: 5},
+		{step: 2, max: 10, priority: 5, count: 1, want: 7},
+		{step: 2, max: 10, priority: 5, count: 2, want: 9},
+		{step: 2, max: 10, priority: 5, count: 3, want: 10},
+		{step: 2, max: 8, priority: 9, count: 1, want: 9},
+	}
+
+	for _, tt := range tests {
+		r := newRenotifier(time.Hour, tt.step, tt.max, time.Hour)
+		got := r.priority(reminder{outbound: GotifyNotification{Priority: tt.priority}, count: tt.count})
+		if got != tt.want {
+			t.Errorf("priority %d after %d reminders with step %d up to %d = %d, want %d", tt.priority, tt.count, tt.step, tt.max, got, tt.want)
+		}
+	}
+}
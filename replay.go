@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// replayQueue keeps the last notifications gotify did not accept, so they can be sent again
+// once gotify is back instead of being lost. When a path is set, the queue is persisted as
+// JSON so it survives restarts of the bridge
+type replayQueue struct {
+	size        int
+	maxAttempts int
+	path        string
+
+	mu       sync.Mutex
+	messages []heldMessage
+}
+
+/* A queued notification as persisted, with the webhook path of the bridge it belongs to */
+type storedReplay struct {
+	Path         string               `json:"path"`
+	Token        string               `json:"token"`
+	Alert        Alert                `json:"alert"`
+	Group        []storedGroupedAlert `json:"group,omitempty"`
+	Notification GotifyNotification   `json:"notification"`
+	Attempts     int                  `json:"attempts"`
+}
+
+type storedGroupedAlert struct {
+	Alert        Alert              `json:"alert"`
+	Notification GotifyNotification `json:"notification"`
+}
+
+type replayStatus struct {
+	Pending  int `json:"pending"`
+	Replayed int `json:"replayed"`
+}
+
+func newReplayQueue(size int, maxAttempts int, path string) *replayQueue {
+	return &replayQueue{size: size, maxAttempts: maxAttempts, path: path}
+}
+
+// load reads the notifications persisted before a restart. svr resolves the webhook paths
+// they were received on, notifications of endpoints that no longer exist are dropped
+func (q *replayQueue) load(svr *bridge) error {
+	if q.path == "" || q.size <= 0 {
+		return nil
+	}
+	b, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) || len(b) == 0 {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read replay store %s: %w", q.path, err)
+	}
+	stored := []storedReplay{}
+	if err = json.Unmarshal(b, &stored); err != nil {
+		return fmt.Errorf("unable to parse replay store %s: %w", q.path, err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, r := range stored {
+		owner, err := svr.pathBridge(r.Path)
+		if err != nil {
+			slog.Warn("Dropping persisted failed notification", "fingerprint", r.Alert.Fingerprint, "title", r.Notification.Title, "error", err)
+			continue
+		}
+		h := heldMessage{svr: owner, alert: r.Alert, token: r.Token, outbound: r.Notification, attempts: r.Attempts}
+		for _, g := range r.Group {
+			h.group = append(h.group, groupedAlert{alert: g.Alert, notification: g.Notification, token: r.Token})
+		}
+		q.messages = append(q.messages, h)
+	}
+	if len(q.messages) > q.size {
+		q.messages = q.messages[len(q.messages)-q.size:]
+	}
+	slog.Info("Loaded failed notifications to replay", "count", len(q.messages), "path", q.path)
+	return nil
+}
+
+/* Callers must hold the lock */
+func (q *replayQueue) save() {
+	if q.path == "" {
+		return
+	}
+
+	stored := make([]storedReplay, 0, len(q.messages))
+	for _, h := range q.messages {
+		r := storedReplay{Path: *h.svr.webhookPath, Token: h.token, Alert: h.alert, Notification: h.outbound, Attempts: h.attempts}
+		for _, g := range h.group {
+			r.Group = append(r.Group, storedGroupedAlert{Alert: g.alert, Notification: g.notification})
+		}
+		stored = append(stored, r)
+	}
+	b, err := json.Marshal(stored)
+	if err == nil {
+		/* Write to a temporary file first so a crash never leaves a truncated store behind */
+		tmp := q.path + ".tmp"
+		if err = os.WriteFile(tmp, b, 0600); err == nil {
+			err = os.Rename(tmp, q.path)
+		}
+	}
+	if err != nil {
+		slog.Warn("Unable to persist failed notifications", "path", q.path, "error", err)
+	}
+}
+
+// keep adds a notification that failed to the queue, replacing an earlier one of the same alert
+// as only its latest state is of interest. The oldest notification is dropped once the queue
+// is full, and a notification that was replayed maxAttempts times is not kept again. Whether
+// the notification was kept is returned, which it never is when replaying is disabled
+func (q *replayQueue) keep(h heldMessage) bool {
+	if q == nil || q.size <= 0 {
+		return false
+	}
+	if q.maxAttempts > 0 && h.attempts >= q.maxAttempts {
+		h.count("alerts_replay_dropped")
+		slog.Warn("Dropping failed notification after replaying it too often", "fingerprint", h.alert.Fingerprint, "title", h.outbound.Title, "attempts", h.attempts)
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.save()
+	q.remove(h.alert.Fingerprint)
+	q.messages = append(q.messages, h)
+	if len(q.messages) > q.size {
+		dropped := q.messages[0]
+		q.messages = q.messages[1:]
+		dropped.count("alerts_replay_dropped")
+		slog.Warn("Replay queue full - dropping oldest failed notification", "fingerprint", dropped.alert.Fingerprint, "title", dropped.outbound.Title)
+	}
+	return true
+}
+
+// forget removes the failed notification of an alert, e.g. because a newer one was sent
+func (q *replayQueue) forget(fingerprint string) {
+	if q == nil || fingerprint == "" {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.remove(fingerprint) {
+		q.save()
+	}
+}
+
+/* Callers must hold the lock */
+func (q *replayQueue) remove(fingerprint string) bool {
+	if fingerprint == "" {
+		return false
+	}
+	for i, m := range q.messages {
+		if m.alert.Fingerprint == fingerprint {
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (q *replayQueue) count() int {
+	if q == nil {
+		return 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+// replay sends the queued notifications again in the order they failed and returns how many
+// gotify accepted. Those failing again stay queued until they were sent maxAttempts times
+func (q *replayQueue) replay() int {
+	q.mu.Lock()
+	messages := q.messages
+	q.messages = nil
+	q.mu.Unlock()
+
+	if len(messages) == 0 {
+		return 0
+	}
+	for i := range messages {
+		messages[i].attempts++
+	}
+	slog.Info("Replaying failed notifications", "count", len(messages))
+	sent := sendHeld(messages)
+
+	/* The store keeps the notifications until they were all sent or queued again */
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.save()
+	return sent
+}
+
+// run replays the queued notifications every interval while gotify is reachable, until the
+// bridge exits. Gotify may reject messages while its health is fine, or be down only between
+// two checks, so any reachable gotify is worth another attempt
+func (q *replayQueue) run(svr *bridge, interval time.Duration) {
+	for range time.Tick(interval) {
+		if q.count() == 0 || svr.pause.active() {
+			continue
+		}
+
+		if up, _ := svr.cachedGotifyHealth(); up {
+			q.replay()
+		}
+	}
+}
+
+// handleReplay sends the failed notifications again on POST and returns how many are still
+// queued on GET
+func (svr *bridge) handleReplay(w http.ResponseWriter, r *http.Request) {
+	status := replayStatus{}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		status.Replayed = svr.replay.replay()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	status.Pending = svr.replay.count()
+	writeJSON(w, status)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newReplayTestBridge(gotify *fakeGotify, maxAttempts int, path string) *bridge {
+	svr := newDispatchTestBridge(gotify.URL)
+	webhookPath := "/gotify_webhook"
+	svr.webhookPath = &webhookPath
+	svr.replay = newReplayQueue(3, maxAttempts, path)
+	return svr
+}
+
+func replayTestMessage(svr *bridge, fingerprint string, token string) heldMessage {
+	return heldMessage{
+		svr:      svr,
+		alert:    Alert{Fingerprint: fingerprint, Status: "firing", Labels: map[string]string{"alertname": fingerprint}},
+		token:    token,
+		outbound: GotifyNotification{Title: fingerprint, Message: "failed", Priority: 5},
+	}
+}
+
+/* Fingerprints of the queued notifications, oldest first */
+func queuedFingerprints(q *replayQueue) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fingerprints := []string{}
+	for _, m := range q.messages {
+		fingerprints = append(fingerprints, m.alert.Fingerprint)
+	}
+	return fingerprints
+}
+
+func TestReplayOrder(t *testing.T) {
+	gotify := newFakeGotify(t, 200, 1)
+	svr := newReplayTestBridge(gotify, 0, "")
+
+	svr.replay.keep(replayTestMessage(svr, "a1", "first"))
+	svr.replay.keep(replayTestMessage(svr, "a2", "second"))
+	svr.replay.keep(replayTestMessage(svr, "a1", "third"))
+	svr.replay.keep(replayTestMessage(svr, "a3", "fourth"))
+	svr.replay.keep(replayTestMessage(svr, "a4", "fifth"))
+	if got := queuedFingerprints(svr.replay); !reflect.DeepEqual(got, []string{"a1", "a3", "a4"}) {
+		t.Fatalf("queued %v, want the latest notification of each alert with the oldest dropped", got)
+	}
+
+	if sent := svr.replay.replay(); sent != 3 {
+		t.Errorf("replay() = %d, want 3", sent)
+	}
+	if got := gotify.received(); !reflect.DeepEqual(got, []string{"third", "fourth", "fifth"}) {
+		t.Errorf("replayed with tokens %v, want them in the order they failed", got)
+	}
+	if n := svr.replay.count(); n != 0 {
+		t.Errorf("%d notifications left after replaying", n)
+	}
+}
+
+func TestReplayMaxAttempts(t *testing.T) {
+	gotify := newFakeGotify(t, 500, 0)
+	svr := newReplayTestBridge(gotify, 2, "")
+	svr.replay.keep(replayTestMessage(svr, "a1", "token"))
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if sent := svr.replay.replay(); sent != 0 {
+			t.Fatalf("attempt %d: replay() = %d, want 0", attempt, sent)
+		}
+		if want := 2 - attempt; svr.replay.count() != want {
+			t.Errorf("attempt %d: %d notifications queued, want %d", attempt, svr.replay.count(), want)
+		}
+	}
+	if n := len(gotify.received()); n != 2 {
+		t.Errorf("gotify received %d attempts, want 2", n)
+	}
+
+	/* A new failure of the alert starts over */
+	svr.replay.keep(replayTestMessage(svr, "a1", "token"))
+	gotify.answer(200)
+	if sent := svr.replay.replay(); sent != 1 {
+		t.Errorf("replay() after a new failure = %d, want 1", sent)
+	}
+}
+
+func TestReplayStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.json")
+	gotify := newFakeGotify(t, 500, 0)
+	svr := newReplayTestBridge(gotify, 5, path)
+
+	svr.replay.keep(replayTestMessage(svr, "a1", "token-1"))
+	group := []groupedAlert{{alert: Alert{Fingerprint: "g1", Status: "resolved"}, notification: GotifyNotification{Title: "g1"}, token: "token-2"}}
+	svr.replay.keep(heldMessage{svr: svr, group: group, token: "token-2", outbound: GotifyNotification{Title: "group"}})
+	svr.replay.replay()
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("replay store %s: %v, %v", path, info, err)
+	}
+
+	restarted := newReplayTestBridge(gotify, 5, path)
+	if err := restarted.replay.load(restarted); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	restarted.replay.mu.Lock()
+	loaded := restarted.replay.messages
+	restarted.replay.mu.Unlock()
+	if len(loaded) != 2 {
+		t.Fatalf("loaded %d notifications, want 2", len(loaded))
+	}
+	if m := loaded[0]; m.svr != restarted || m.token != "token-1" || m.attempts != 1 || !reflect.DeepEqual(m.alert, replayTestMessage(svr, "a1", "").alert) {
+		t.Errorf("loaded %+v", m)
+	}
+	if m := loaded[1]; len(m.group) != 1 || m.group[0].alert.Fingerprint != "g1" || m.group[0].token != "token-2" || m.outbound.Title != "group" {
+		t.Errorf("loaded group %+v", m)
+	}
+
+	gotify.answer(200)
+	restarted.replay.replay()
+	again := newReplayTestBridge(gotify, 5, path)
+	if err := again.replay.load(again); err != nil {
+		t.Fatal(err)
+	}
+	if n := again.replay.count(); n != 0 {
+		t.Errorf("replay store still holds %d sent notifications", n)
+	}
+}
+
+func TestReplayStoreUnknownEndpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.json")
+	os.WriteFile(path, []byte(`[{"path":"/removed","token":"t","alert":{"status":"firing","fingerprint":"a1"},"notification":{"title":"x"}}]`), 0600)
+
+	svr := newReplayTestBridge(newFakeGotify(t, 200, 1), 0, path)
+	if err := svr.replay.load(svr); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if n := svr.replay.count(); n != 0 {
+		t.Errorf("loaded %d notifications of a removed endpoint", n)
+	}
+
+	os.WriteFile(path, []byte(`not json`), 0600)
+	if err := svr.replay.load(svr); err == nil {
+		t.Error("load() accepted an invalid store")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// urlRewrite replaces the parts of a URL matching a regular expression, e.g. to turn the
+// internal address of Prometheus into one reachable from outside the cluster
+type urlRewrite struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// parseURLRewrites reads the rules of --url_rewrite, exiting when a rule is invalid
+func parseURLRewrites(specs []string) []urlRewrite {
+	rewrites, err := urlRewrites(specs)
+	if err != nil {
+		slog.Error("Invalid --url_rewrite", "error", err)
+		os.Exit(1)
+	}
+	return rewrites
+}
+
+/* Rules are REGEX=REPLACEMENT, split at the first = so the replacement may hold a query string */
+func urlRewrites(specs []string) ([]urlRewrite, error) {
+	rewrites := make([]urlRewrite, 0, len(specs))
+	for _, spec := range specs {
+		pattern, replacement, ok := strings.Cut(spec, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid rule '%s' - expected REGEX=REPLACEMENT", spec)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression in rule '%s': %w", spec, err)
+		}
+		rewrites = append(rewrites, urlRewrite{re: re, replacement: replacement})
+	}
+	return rewrites, nil
+}
+
+// rewriteURL applies all rules to a URL in the order they were given
+func (svr *bridge) rewriteURL(u string) string {
+	if u == "" {
+		return u
+	}
+	for _, rewrite := range svr.urlRewrites {
+		u = rewrite.re.ReplaceAllString(u, rewrite.replacement)
+	}
+	return u
+}
+
+// rewriteURLs applies --url_rewrite to the generator and external URLs of all alerts before
+// they are used in links, extras and templates
+func (svr *bridge) rewriteURLs(n *Notification) {
+	if len(svr.urlRewrites) == 0 {
+		return
+	}
+	for i := range n.Alerts {
+		a := &n.Alerts[i]
+		a.GeneratorURL = svr.rewriteURL(a.GeneratorURL)
+		a.ExternalURL = svr.rewriteURL(a.ExternalURL)
+	}
+	n.ExternalURL = svr.rewriteURL(n.ExternalURL)
+}
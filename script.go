@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+/* Functions of the base library that would reach the file system or load other code */
+var scriptUnsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module"}
+
+// alertScript runs a Lua script for every alert about to be sent. The script sees the alert as
+// the global alert and may change the fields of the global notification, returning false
+// drops the alert. Only the base, table, string and math libraries are available, and every
+// call gets a fresh state limited to the timeout
+type alertScript struct {
+	path    string
+	timeout time.Duration
+	proto   *lua.FunctionProto
+}
+
+func newAlertScript(path string, timeout time.Duration) (*alertScript, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := parse.Parse(strings.NewReader(string(source)), path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse script %s: %w", path, err)
+	}
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile script %s: %w", path, err)
+	}
+	return &alertScript{path: path, timeout: timeout, proto: proto}, nil
+}
+
+func (s *alertScript) newState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true, CallStackSize: 256, RegistryMaxSize: 256 * 1024})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range scriptUnsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	return L
+}
+
+// run passes an alert and its notification to the script and reports whether the script dropped
+// the alert. Changes of the script are applied to outbound only when it succeeded
+func (s *alertScript) run(ctx context.Context, alert Alert, outbound *GotifyNotification) (drop bool, err error) {
+	L := s.newState()
+	defer L.Close()
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	L.SetGlobal("alert", toLuaValue(L, map[string]interface{}{
+		"status":       alert.Status,
+		"labels":       alert.Labels,
+		"annotations":  alert.Annotations,
+		"startsAt":     alert.StartsAt,
+		"endsAt":       alert.EndsAt,
+		"generatorURL": alert.GeneratorURL,
+		"fingerprint":  alert.Fingerprint,
+		"receiver":     alert.Receiver,
+	}))
+	notification := toLuaValue(L, map[string]interface{}{
+		"title":    outbound.Title,
+		"message":  outbound.Message,
+		"priority": outbound.Priority,
+		"extras":   outbound.Extras,
+	}).(*lua.LTable)
+	L.SetGlobal("notification", notification)
+
+	L.Push(L.NewFunctionFromProto(s.proto))
+	if err := L.PCall(0, 1, nil); err != nil {
+		return false, err
+	}
+	if L.Get(-1) == lua.LFalse {
+		return true, nil
+	}
+
+	changed := GotifyNotification{
+		Title:   lua.LVAsString(notification.RawGetString("title")),
+		Message: lua.LVAsString(notification.RawGetString("message")),
+	}
+	priority, ok := notification.RawGetString("priority").(lua.LNumber)
+	if !ok {
+		return false, fmt.Errorf("priority of notification is not a number")
+	}
+	changed.Priority = int(priority)
+	switch extras := fromLuaValue(notification.RawGetString("extras")).(type) {
+	case map[string]interface{}:
+		changed.Extras = extras
+	case nil:
+		changed.Extras = map[string]interface{}{}
+	default:
+		return false, fmt.Errorf("extras of notification is not a table")
+	}
+	*outbound = changed
+	return false, nil
+}
+
+// applyScript runs the script for an alert about to be sent and reports whether the script
+// dropped it. A failing script leaves the notification as it is, so alerts still arrive
+func (svr *bridge) applyScript(ctx context.Context, logger *slog.Logger, alert Alert, outbound *GotifyNotification) (drop bool) {
+	if svr.script == nil {
+		return false
+	}
+
+	drop, err := svr.script.run(ctx, alert, outbound)
+	if err != nil {
+		logger.Warn("Script failed - sending the notification unchanged", "script", svr.script.path, "error", err)
+		metrics.Inc("script_errors")
+		return false
+	}
+	return drop
+}
+
+/* Converts maps, slices and scalars as found in decoded JSON to Lua values */
+func toLuaValue(L *lua.LState, value interface{}) lua.LValue {
+	switch v := value.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case int:
+		return lua.LNumber(v)
+	case float64:
+		return lua.LNumber(v)
+	case map[string]string:
+		table := L.NewTable()
+		for key, item := range v {
+			table.RawSetString(key, lua.LString(item))
+		}
+		return table
+	case map[string]interface{}:
+		table := L.NewTable()
+		for key, item := range v {
+			table.RawSetString(key, toLuaValue(L, item))
+		}
+		return table
+	case []interface{}:
+		table := L.NewTable()
+		for _, item := range v {
+			table.Append(toLuaValue(L, item))
+		}
+		return table
+	default:
+		return lua.LString(fmt.Sprint(v))
+	}
+}
+
+/* Converts Lua values back, tables with a sequence become slices and all others maps */
+func fromLuaValue(value lua.LValue) interface{} {
+	switch v := value.(type) {
+	case lua.LBool:
+		return bool(v)
+	case lua.LString:
+		return string(v)
+	case lua.LNumber:
+		return float64(v)
+	case *lua.LTable:
+		if v.MaxN() > 0 {
+			items := []interface{}{}
+			v.ForEach(func(_ lua.LValue, item lua.LValue) {
+				items = append(items, fromLuaValue(item))
+			})
+			return items
+		}
+		items := map[string]interface{}{}
+		v.ForEach(func(key lua.LValue, item lua.LValue) {
+			items[key.String()] = fromLuaValue(item)
+		})
+		return items
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// verifySignature checks the HMAC-SHA256 of a webhook body against the hex encoded signature
+// sent along with it. The signature may be prefixed with sha256=, as GitHub and others do
+func verifySignature(secret []byte, signature string, body []byte) error {
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	if signature == "" {
+		return errors.New("signature missing")
+	}
+
+	sent, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.New("signature is not hex encoded")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(sent, mac.Sum(nil)) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
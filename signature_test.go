@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func signBody(secret string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"status":"firing"}`)
+	valid := signBody("s3cret", string(body))
+
+	/* Senders differ in how they write the signature */
+	for _, signature := range []string{valid, "sha256=" + valid, " " + valid + "\n", strings.ToUpper(valid)} {
+		if err := verifySignature(secret, signature, body); err != nil {
+			t.Errorf("verifySignature(%q) error = %v", signature, err)
+		}
+	}
+
+	for _, signature := range []string{"", "sha256=", "not-hex", valid[:32], signBody("other", string(body))} {
+		if err := verifySignature(secret, signature, body); err == nil {
+			t.Errorf("verifySignature(%q) accepted an invalid signature", signature)
+		}
+	}
+	if err := verifySignature(secret, valid, []byte(`{"status":"resolved"}`)); err == nil {
+		t.Error("verifySignature() accepted the signature of another body")
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var matcherValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// SilenceURL returns the page of Alertmanager creating a new silence, pre-filled with a
+// matcher for every label of the alert. It is empty when the external URL of Alertmanager
+// is unknown
+func (a Alert) SilenceURL() string {
+	if !strings.HasPrefix(a.ExternalURL, "http") || len(a.Labels) == 0 {
+		return ""
+	}
+
+	matchers := make([]string, 0, len(a.Labels))
+	for _, name := range sortedKeys(a.Labels) {
+		matchers = append(matchers, fmt.Sprintf(`%s="%s"`, name, matcherValueEscaper.Replace(a.Labels[name])))
+	}
+	filter := "{" + strings.Join(matchers, ", ") + "}"
+	return strings.TrimSuffix(a.ExternalURL, "/") + "/#/silences/new?filter=" + strings.ReplaceAll(url.QueryEscape(filter), "+", "%20")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleSimple sends the plain text body of a request as message, so shell scripts can notify
+// Gotify with nothing but curl. The title, priority, token and a click URL are given as query
+// parameters, e.g. /simple?title=backup&priority=7
+func (svr *bridge) handleSimple(w http.ResponseWriter, r *http.Request) {
+	log := contextLogger(r.Context())
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		http.Error(w, "Only POST and PUT are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	if *maxRequestBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, *maxRequestBytes)
+	}
+	body, err := decodeBody(r, body, *maxRequestBytes)
+	var text []byte
+	if err == nil {
+		text, err = io.ReadAll(body)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		rejectTooLarge(log, w, r, tooLarge)
+		return
+	}
+	var notification Notification
+	if err == nil {
+		notification, err = svr.simpleNotification(string(text), r.URL.Query())
+	}
+	if err != nil {
+		log.Warn("Invalid message", "error", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		metrics.Inc("requests_invalid")
+		return
+	}
+
+	token := svr.gotifyToken.Get()
+	if appToken, _ := svr.requestToken(r); appToken != "" {
+		token = appToken
+	}
+	respCode, response := svr.deliver(r.Context(), token, notification, text)
+	http.Error(w, strings.Join(response, "\n"), respCode)
+}
+
+// simpleNotification converts a plain text message into a firing alert named Simple. The
+// message parameter takes the place of an empty body
+func (svr *bridge) simpleNotification(text string, params url.Values) (Notification, error) {
+	message := strings.TrimSpace(text)
+	if message == "" {
+		message = strings.TrimSpace(params.Get("message"))
+	}
+	if message == "" {
+		return Notification{}, fmt.Errorf("no message sent")
+	}
+	title := params.Get("title")
+	if title == "" {
+		title = "Message"
+	}
+
+	alert := Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "Simple"},
+		Annotations: map[string]string{*svr.titleAnnotation: title, *svr.messageAnnotation: message},
+		StartsAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if value := params.Get("priority"); value != "" {
+		if _, err := strconv.Atoi(value); err != nil {
+			return Notification{}, fmt.Errorf("priority '%s' is no number", value)
+		}
+		if *svr.priorityAnnotation != "" {
+			alert.Annotations[*svr.priorityAnnotation] = value
+		}
+	}
+	if *extrasAnnotation != "" {
+		if params.Get("markdown") == "true" || params.Get("markdown") == "1" {
+			alert.Annotations[*extrasAnnotation+"::client::display::contentType"] = "text/markdown"
+		}
+		if link := params.Get("url"); link != "" {
+			alert.Annotations[*extrasAnnotation+"::client::notification::click::url"] = link
+		}
+	}
+
+	return singleAlert("simple", "simple:"+title, alert), nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackMessage is the payload of a Slack incoming webhook. Only what can be shown by Gotify is
+// read, interactive elements of blocks are skipped
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Username    string            `json:"username"`
+	Attachments []slackAttachment `json:"attachments"`
+	Blocks      []slackBlock      `json:"blocks"`
+}
+
+type slackAttachment struct {
+	Fallback  string `json:"fallback"`
+	Color     string `json:"color"`
+	Pretext   string `json:"pretext"`
+	Title     string `json:"title"`
+	TitleLink string `json:"title_link"`
+	Text      string `json:"text"`
+	Fields    []struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+	} `json:"fields"`
+	ImageURL string       `json:"image_url"`
+	Footer   string       `json:"footer"`
+	Blocks   []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text"`
+	Fields   []slackText `json:"fields"`
+	Elements []slackText `json:"elements"`
+	ImageURL string      `json:"image_url"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+/* Slack colors attachments with these names or a hex code */
+var slackColors = map[string]string{"danger": "#ff0000", "warning": "#ffa500", "good": "#00ff00"}
+
+// parseSlack converts the message of a Slack incoming webhook into a firing alert with the
+// mrkdwn of Slack translated to Markdown. The color of the first colored attachment sets the
+// severity and priority
+func parseSlack(svr *bridge, body []byte) (Notification, error) {
+	/* Slack also accepts the JSON form encoded as the payload parameter */
+	if form, err := url.ParseQuery(string(body)); err == nil && form.Has("payload") {
+		body = []byte(form.Get("payload"))
+	}
+	var msg slackMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return Notification{}, fmt.Errorf("invalid Slack message: %w", err)
+	}
+
+	blockParts, title, image := slackBlocks(msg.Blocks)
+	parts := append([]string{slackMarkdown(msg.Text)}, blockParts...)
+	color := ""
+
+	for _, attachment := range msg.Attachments {
+		if color == "" {
+			color = attachment.Color
+		}
+		if image == "" {
+			image = attachment.ImageURL
+		}
+		shown := len(parts)
+		parts = append(parts, slackMarkdown(attachment.Pretext))
+		if attachment.Title != "" {
+			if title == "" {
+				title = slackPlain(attachment.Title)
+			}
+			if attachment.TitleLink != "" {
+				parts = append(parts, fmt.Sprintf("**[%s](%s)**", slackPlain(attachment.Title), attachment.TitleLink))
+			} else {
+				parts = append(parts, "**"+slackPlain(attachment.Title)+"**")
+			}
+		}
+		parts = append(parts, slackMarkdown(attachment.Text))
+		fields := []string{}
+		for _, field := range attachment.Fields {
+			fields = append(fields, fmt.Sprintf("**%s**: %s", slackPlain(field.Title), slackMarkdown(field.Value)))
+		}
+		parts = append(parts, strings.Join(fields, "  \n"))
+		attachmentBlocks, attachmentHeader, attachmentImage := slackBlocks(attachment.Blocks)
+		parts = append(parts, attachmentBlocks...)
+		if title == "" {
+			title = attachmentHeader
+		}
+		if image == "" {
+			image = attachmentImage
+		}
+		parts = append(parts, slackMarkdown(attachment.Footer))
+		if strings.TrimSpace(strings.Join(parts[shown:], "")) == "" {
+			parts = append(parts, attachment.Fallback)
+		}
+	}
+
+	message := joinParts(parts)
+	if message == "" {
+		return Notification{}, fmt.Errorf("invalid Slack message: neither text, attachments nor blocks are set")
+	}
+	if title == "" {
+		title = msg.Username
+	}
+	if title == "" {
+		title = "Slack"
+	}
+
+	alert := Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "Slack"},
+		Annotations: map[string]string{*svr.titleAnnotation: title, *svr.messageAnnotation: message},
+		StartsAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if msg.Username != "" {
+		alert.Labels["username"] = msg.Username
+	}
+	if *extrasAnnotation != "" {
+		alert.Annotations[*extrasAnnotation+"::client::display::contentType"] = "text/markdown"
+	}
+	if image != "" && *imageAnnotation != "" {
+		alert.Annotations[*imageAnnotation] = image
+	}
+	if red, green, blue, ok := slackColor(color); ok {
+		severity, priority := colorPriority(red, green, blue)
+		alert.Labels[*svr.severityLabel] = severity
+		svr.inputPriority(alert, priority)
+	}
+
+	return singleAlert("slack", "slack:"+alert.Labels["alertname"], alert), nil
+}
+
+/* Renders blocks as Markdown, returning the text of the first header and the first image */
+func slackBlocks(blocks []slackBlock) ([]string, string, string) {
+	parts, header, image := []string{}, "", ""
+	for _, block := range blocks {
+		switch block.Type {
+		case "header":
+			if block.Text != nil && header == "" {
+				header = slackPlain(block.Text.Text)
+			}
+		case "section":
+			if block.Text != nil {
+				parts = append(parts, block.Text.markdown())
+			}
+			fields := []string{}
+			for _, field := range block.Fields {
+				fields = append(fields, field.markdown())
+			}
+			parts = append(parts, strings.Join(fields, "  \n"))
+		case "context":
+			texts := []string{}
+			for _, element := range block.Elements {
+				if element.Type == "mrkdwn" || element.Type == "plain_text" {
+					texts = append(texts, element.markdown())
+				}
+			}
+			parts = append(parts, strings.Join(texts, " "))
+		case "divider":
+			parts = append(parts, "---")
+		case "image":
+			if image == "" {
+				image = block.ImageURL
+			}
+		}
+	}
+	return parts, header, image
+}
+
+func (t slackText) markdown() string {
+	if t.Type == "plain_text" {
+		return slackPlain(t.Text)
+	}
+	return slackMarkdown(t.Text)
+}
+
+func joinParts(parts []string) string {
+	kept := []string{}
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "\n\n")
+}
+
+var (
+	slackLink   = regexp.MustCompile(`<([^<>|]+)(?:\|([^<>]*))?>`)
+	slackBold   = regexp.MustCompile(`(^|[^\w*])\*([^*\n]+)\*($|[^\w*])`)
+	slackItalic = regexp.MustCompile(`(^|[^\w_])_([^_\n]+)_($|[^\w_])`)
+	slackStrike = regexp.MustCompile(`(^|[^\w~])~([^~\n]+)~($|[^\w~])`)
+)
+
+// slackMarkdown translates mrkdwn of Slack to Markdown: *bold*, _italic_ and ~strike~ are
+// changed, links, mentions and the HTML entities Slack escapes with are resolved
+func slackMarkdown(s string) string {
+	s = slackLink.ReplaceAllStringFunc(s, func(match string) string {
+		parts := slackLink.FindStringSubmatch(match)
+		target, label := parts[1], parts[2]
+		switch {
+		case strings.HasPrefix(target, "!"):
+			/* Special mentions such as <!here> or <!subteam^ID|@team> */
+			if label != "" {
+				return label
+			}
+			return "@" + strings.SplitN(target[1:], "^", 2)[0]
+		case strings.HasPrefix(target, "@"), strings.HasPrefix(target, "#"):
+			if label != "" {
+				return target[:1] + strings.TrimPrefix(label, target[:1])
+			}
+			return target
+		case label != "":
+			return fmt.Sprintf("[%s](%s)", label, target)
+		}
+		return target
+	})
+	s = replaceSpans(slackBold, s, "$1**$2**$3")
+	s = replaceSpans(slackItalic, s, "$1*$2*$3")
+	s = replaceSpans(slackStrike, s, "$1~~$2~~$3")
+	return html.UnescapeString(s)
+}
+
+/* Spans separated by a single character share it as delimiter, so a second pass catches every other one */
+func replaceSpans(re *regexp.Regexp, s string, replacement string) string {
+	return re.ReplaceAllString(re.ReplaceAllString(s, replacement), replacement)
+}
+
+func slackPlain(s string) string {
+	return html.UnescapeString(s)
+}
+
+func slackColor(color string) (int, int, int, bool) {
+	if named, ok := slackColors[color]; ok {
+		color = named
+	}
+	color = strings.TrimPrefix(color, "#")
+	if len(color) != 6 {
+		return 0, 0, 0, false
+	}
+	value, err := strconv.ParseUint(color, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(value >> 16), int(value >> 8 & 0xff), int(value & 0xff), true
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestSlackMarkdown(t *testing.T) {
+	for input, want := range map[string]string{
+		"Disk is full":              "Disk is full",
+		"Disk is *full*":            "Disk is **full**",
+		"_maybe_ full":              "*maybe* full",
+		"~not~ full":                "~~not~~ full",
+		"*a* *b* *c*":               "**a** **b** **c**",
+		"snake_case_name and 2*3*4": "snake_case_name and 2*3*4",
+		"See <https://grafana/d/1|the dashboard>": "See [the dashboard](https://grafana/d/1)",
+		"<https://grafana/d/1>":                   "https://grafana/d/1",
+		"ping <@U123>":                            "ping @U123",
+		"ping <@U123|alice>":                      "ping @alice",
+		"in <#C123|#ops>":                         "in #ops",
+		"<!here> look":                            "@here look",
+		"<!subteam^S123|@oncall> look":            "@oncall look",
+		"<!subteam^S123>":                         "@subteam",
+		"a &lt; b &amp;&amp; c &gt; d":            "a < b && c > d",
+	} {
+		if got := slackMarkdown(input); got != want {
+			t.Errorf("slackMarkdown(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSlackColor(t *testing.T) {
+	if r, g, b, ok := slackColor("danger"); !ok || r != 255 || g != 0 || b != 0 {
+		t.Errorf("slackColor(danger) = %d, %d, %d, %v", r, g, b, ok)
+	}
+	if r, g, b, ok := slackColor("#ffa500"); !ok || r != 255 || g != 165 || b != 0 {
+		t.Errorf("slackColor(#ffa500) = %d, %d, %d, %v", r, g, b, ok)
+	}
+	if _, g, _, ok := slackColor("good"); !ok || g != 255 {
+		t.Errorf("slackColor(good) = %d, %v", g, ok)
+	}
+	if _, _, b, ok := slackColor("0000ff"); !ok || b != 255 {
+		t.Errorf("slackColor(0000ff) = %d, %v", b, ok)
+	}
+	for _, color := range []string{"#fff", "#gggggg", ""} {
+		if _, _, _, ok := slackColor(color); ok {
+			t.Errorf("slackColor(%q) accepted an invalid color", color)
+		}
+	}
+}
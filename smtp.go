@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// smtpServer receives mails of devices that can't do anything but send mails and dispatches
+// them like webhook calls. It speaks just enough SMTP for that - there is neither STARTTLS nor
+// AUTH, so it should only be reachable from trusted networks
+type smtpServer struct {
+	address string
+	senders []string
+	maxSize int64
+	timeout time.Duration
+}
+
+func newSMTPServer(address string, senders []string, maxSize int64) (*smtpServer, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("invalid SMTP address '%s': %w", address, err)
+	}
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("the maximum mail size must be positive")
+	}
+	for _, sender := range senders {
+		if _, err := path.Match(sender, ""); err != nil {
+			return nil, fmt.Errorf("invalid sender pattern '%s': %w", sender, err)
+		}
+	}
+	return &smtpServer{address: address, senders: senders, maxSize: maxSize, timeout: 5 * time.Minute}, nil
+}
+
+func (s *smtpServer) run(svr *bridge) {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		slog.Error("Error starting the SMTP server", "listen", s.address, "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Receiving mails", "listen", s.address, "senders", s.senders)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			slog.Warn("Unable to accept SMTP connection", "error", err)
+			continue
+		}
+		go s.serve(svr, conn)
+	}
+}
+
+// allowed reports whether mails of the envelope sender are accepted
+func (s *smtpServer) allowed(sender string) bool {
+	if len(s.senders) == 0 {
+		return true
+	}
+	for _, pattern := range s.senders {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(sender)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+/* The address of MAIL FROM:<a@b> SIZE=123 or RCPT TO:<a@b> */
+var smtpPath = regexp.MustCompile(`(?i)^(?:MAIL FROM|RCPT TO):\s*<([^>]*)>`)
+
+// serve handles one SMTP session. Mails are dispatched once their data was received, with the
+// token of the application the local part of each recipient names
+func (s *smtpServer) serve(svr *bridge, conn net.Conn) {
+	defer conn.Close()
+	log := slog.With("remote_addr", conn.RemoteAddr().String())
+	text := textproto.NewConn(conn)
+	reply := func(code int, message string) bool {
+		conn.SetDeadline(time.Now().Add(s.timeout))
+		return text.PrintfLine("%d %s", code, message) == nil
+	}
+
+	var sender string
+	var recipients []string
+	reply(220, "alertmanager_gotify_bridge ESMTP ready")
+	for {
+		conn.SetDeadline(time.Now().Add(s.timeout))
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "HELO":
+			reply(250, "alertmanager_gotify_bridge")
+		case "EHLO":
+			text.PrintfLine("250-alertmanager_gotify_bridge")
+			text.PrintfLine("250-8BITMIME")
+			reply(250, fmt.Sprintf("SIZE %d", s.maxSize))
+		case "MAIL":
+			match := smtpPath.FindStringSubmatch(line)
+			switch {
+			case match == nil:
+				reply(501, "Syntax: MAIL FROM:<address>")
+			case !s.allowed(match[1]):
+				log.Warn("Mail of sender not allowed", "sender", match[1])
+				metrics.Inc("requests_invalid")
+				reply(550, "Sender not allowed")
+			default:
+				sender, recipients = match[1], nil
+				reply(250, "OK")
+			}
+		case "RCPT":
+			match := smtpPath.FindStringSubmatch(line)
+			if match == nil {
+				reply(501, "Syntax: RCPT TO:<address>")
+			} else if sender == "" {
+				reply(503, "MAIL first")
+			} else {
+				recipients = append(recipients, match[1])
+				reply(250, "OK")
+			}
+		case "DATA":
+			if len(recipients) == 0 {
+				reply(503, "RCPT first")
+				continue
+			}
+			reply(354, "End data with <CR><LF>.<CR><LF>")
+			dot := text.DotReader()
+			data, err := io.ReadAll(io.LimitReader(dot, s.maxSize+1))
+			if err != nil {
+				return
+			}
+			if int64(len(data)) > s.maxSize {
+				/* The rest of the data has to be read before the client listens again */
+				io.Copy(io.Discard, dot)
+				metrics.Inc("requests_invalid")
+				reply(552, "Message exceeds the maximum size")
+			} else {
+				reply(s.receive(svr, log, sender, recipients, data))
+			}
+			sender, recipients = "", nil
+		case "RSET":
+			sender, recipients = "", nil
+			reply(250, "OK")
+		case "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			reply(502, "Command not implemented")
+		}
+	}
+}
+
+// receive dispatches a mail once for every application its recipients name and returns the
+// SMTP reply. Failures to reach Gotify are temporary, so the device tries again later
+func (s *smtpServer) receive(svr *bridge, log *slog.Logger, sender string, recipients []string, data []byte) (int, string) {
+	metrics.Inc("requests_received")
+	id := newRequestID()
+	ctx := context.WithValue(context.Background(), requestIDKey{}, id)
+	log = log.With("request_id", id, "sender", sender)
+
+	notification, err := svr.mailNotification(sender, data)
+	if err != nil {
+		log.Warn("Invalid mail", "error", err)
+		metrics.Inc("requests_invalid")
+		return 554, err.Error()
+	}
+
+	tokens := map[string]bool{}
+	for _, recipient := range recipients {
+		token := svr.gotifyToken.Get()
+		local := strings.SplitN(recipient, "@", 2)[0]
+		if named, ok := svr.appTokens[strings.ToLower(local)]; ok {
+			token = named
+		}
+		if tokens[token] {
+			continue
+		}
+		tokens[token] = true
+
+		respCode, text := svr.deliver(ctx, token, notification, data)
+		if respCode >= http.StatusBadRequest {
+			log.Warn("Unable to dispatch mail", "recipient", recipient, "status", respCode, "error", strings.Join(text, "; "))
+			return 451, "Unable to dispatch the message - try again later"
+		}
+	}
+	log.Debug("Mail dispatched", "recipients", len(recipients))
+	return 250, "OK queued as " + id
+}
+
+/* X-Priority of mail clients from highest to lowest, normal mails get the default priority */
+var mailPriorities = map[string]int{"1": 8, "2": 7, "4": 3, "5": 1}
+
+// mailNotification converts a mail into a firing alert named Email. The subject is the title
+// and the first text part of the body the message, with HTML mails reduced to their text
+func (svr *bridge) mailNotification(sender string, data []byte) (Notification, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return Notification{}, fmt.Errorf("unable to parse mail: %w", err)
+	}
+	decoder := new(mime.WordDecoder)
+	subject, err := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	body, err := mailText(textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		return Notification{}, fmt.Errorf("unable to read the body of the mail: %w", err)
+	}
+	message := strings.TrimSpace(body)
+	if message == "" {
+		message = subject
+	}
+	if subject == "" {
+		subject = "Email"
+	}
+	if message == "" {
+		return Notification{}, fmt.Errorf("the mail has neither subject nor text")
+	}
+
+	alert := Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "Email", "from": sender},
+		Annotations: map[string]string{*svr.titleAnnotation: subject, *svr.messageAnnotation: message},
+		StartsAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		alert.StartsAt = date.UTC().Format(time.RFC3339)
+	}
+
+	/* X-Priority may be followed by a name, e.g. 1 (Highest) */
+	priority, ok := mailPriorities[strings.SplitN(strings.TrimSpace(msg.Header.Get("X-Priority")), " ", 2)[0]]
+	switch strings.ToLower(msg.Header.Get("Importance")) {
+	case "high":
+		priority, ok = 8, true
+	case "low":
+		priority, ok = 2, true
+	}
+	if ok {
+		svr.inputPriority(alert, priority)
+	}
+
+	return singleAlert("email", "email:"+sender, alert), nil
+}
+
+// mailText returns the text of a mail body, preferring plain text over HTML in multipart mails
+func mailText(header textproto.MIMEHeader, body io.Reader) (string, error) {
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		text, err := io.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		if mediaType == "text/html" {
+			return htmlText(string(text)), nil
+		}
+		if !strings.HasPrefix(mediaType, "text/") {
+			return "", nil
+		}
+		return string(text), nil
+	}
+
+	reader := multipart.NewReader(body, params["boundary"])
+	fallback := ""
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return fallback, nil
+		} else if err != nil {
+			return "", err
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if part.FileName() != "" {
+			continue
+		}
+		text, err := mailText(part.Header, part)
+		if err != nil {
+			return "", err
+		}
+		if partType == "text/plain" || partType == "" || strings.HasPrefix(partType, "multipart/") && text != "" {
+			return text, nil
+		}
+		if fallback == "" {
+			fallback = text
+		}
+	}
+}
+
+var (
+	htmlBreak  = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</tr>|</h\d>`)
+	htmlTag    = regexp.MustCompile(`(?is)<style.*?</style>|<script.*?</script>|<[^>]*>`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+/* Mails in HTML only are shown as their text with the line breaks of paragraphs kept */
+func htmlText(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	lines := strings.Split(html.UnescapeString(s), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
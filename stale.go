@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// staleCheck recognizes alerts that arrive long after they happened, e.g. notifications
+// replayed by Alertmanager after an outage of the bridge or Gotify. Firing alerts are aged by
+// the time they started, resolved alerts by the time they resolved
+type staleCheck struct {
+	maxAge time.Duration
+	mark   bool
+}
+
+func newStaleCheck(maxAge time.Duration, action string) *staleCheck {
+	return &staleCheck{maxAge: maxAge, mark: action == "mark"}
+}
+
+// stale returns the age of an alert that is older than the threshold. Alerts without a
+// timestamp are never stale
+func (s *staleCheck) stale(alert Alert) (age time.Duration, stale bool) {
+	if s == nil {
+		return 0, false
+	}
+
+	at := alert.StartTime()
+	if alert.Status == "resolved" {
+		at = alert.EndTime()
+	}
+	if at.IsZero() {
+		return 0, false
+	}
+	age = time.Since(at)
+	return age, age > s.maxAge
+}
+
+// drops reports whether a stale alert is to be dropped rather than marked
+func (s *staleCheck) drops(alert Alert) bool {
+	_, stale := s.stale(alert)
+	return stale && !s.mark
+}
+
+// markOutbound flags the notification of a stale alert as such
+func (s *staleCheck) markOutbound(alert Alert, outbound *GotifyNotification) bool {
+	age, stale := s.stale(alert)
+	if !stale || !s.mark {
+		return false
+	}
+
+	happened := "started"
+	if alert.Status == "resolved" {
+		happened = "resolved"
+	}
+	outbound.Title = "[STALE] " + outbound.Title
+	outbound.Message += fmt.Sprintf("\n\nThis alert %s %s ago and arrived late, it may be outdated.", happened, age.Round(time.Second))
+	return true
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stormCollapse protects phones from alert storms. When more than threshold alerts are to be
+// sent within window, further alerts are collected instead and sent as a single summary per
+// window until the storm calms down
+type stormCollapse struct {
+	threshold int
+	window    time.Duration
+	priority  int
+
+	mu        sync.Mutex
+	arrivals  []time.Time
+	collapsed []heldMessage
+	active    bool
+}
+
+func newStormCollapse(threshold int, window time.Duration, priority int) (*stormCollapse, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("the storm window must be positive")
+	}
+	return &stormCollapse{threshold: threshold, window: window, priority: priority}, nil
+}
+
+/* Drops the arrivals that left the window, the caller holds the lock */
+func (s *stormCollapse) prune(now time.Time) {
+	keep := 0
+	for keep < len(s.arrivals) && now.Sub(s.arrivals[keep]) > s.window {
+		keep++
+	}
+	s.arrivals = s.arrivals[keep:]
+}
+
+// collapses records an alert that is about to be sent and reports whether it is part of a
+// storm, in which case it is kept for the next summary instead. The bridge and token of the
+// alert decide where the summary goes, as endpoints of --config_file share the storm
+func (s *stormCollapse) collapses(svr *bridge, alert Alert, token string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.prune(now)
+	s.arrivals = append(s.arrivals, now)
+	if !s.active && len(s.arrivals) <= s.threshold {
+		return false
+	}
+	if !s.active {
+		slog.Warn("Alert storm - collapsing alerts into summaries", "threshold", s.threshold, "window", s.window)
+		s.active = true
+	}
+	s.collapsed = append(s.collapsed, heldMessage{svr: svr, alert: alert, token: token})
+	return true
+}
+
+// summaries returns a summary of the alerts collapsed since the last one for every bridge and
+// token they were to be sent with. The storm is over once no more than threshold alerts
+// arrived within the window
+func (s *stormCollapse) summaries() []heldMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	collapsed := s.collapsed
+	s.collapsed = nil
+	over := s.active && len(s.arrivals) <= s.threshold
+	if over {
+		slog.Info("Alert storm is over - sending alerts individually again")
+		s.active = false
+	}
+
+	type destination struct {
+		svr   *bridge
+		token string
+	}
+	order := []destination{}
+	alerts := map[destination][]Alert{}
+	for _, c := range collapsed {
+		d := destination{svr: c.svr, token: c.token}
+		if alerts[d] == nil {
+			order = append(order, d)
+		}
+		alerts[d] = append(alerts[d], c.alert)
+	}
+
+	summaries := []heldMessage{}
+	for _, d := range order {
+		summaries = append(summaries, heldMessage{svr: d.svr, token: d.token, outbound: s.summary(alerts[d], over)})
+	}
+	return summaries
+}
+
+/* Builds the summary of collapsed alerts, counted by alertname */
+func (s *stormCollapse) summary(collapsed []Alert, over bool) GotifyNotification {
+	type counts struct {
+		name             string
+		firing, resolved int
+	}
+	byName := map[string]*counts{}
+	firing, resolved := 0, 0
+	for _, alert := range collapsed {
+		name := alert.Labels["alertname"]
+		if byName[name] == nil {
+			byName[name] = &counts{name: name}
+		}
+		if alert.Status == "resolved" {
+			byName[name].resolved++
+			resolved++
+		} else {
+			byName[name].firing++
+			firing++
+		}
+	}
+	breakdown := make([]*counts, 0, len(byName))
+	for _, c := range byName {
+		breakdown = append(breakdown, c)
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		if a, b := breakdown[i].firing+breakdown[i].resolved, breakdown[j].firing+breakdown[j].resolved; a != b {
+			return a > b
+		}
+		return breakdown[i].name < breakdown[j].name
+	})
+
+	title := fmt.Sprintf("%d alerts firing across %d alertnames", firing, len(byName))
+	if resolved > 0 {
+		title = fmt.Sprintf("%d alerts firing and %d resolved across %d alertnames", firing, resolved, len(byName))
+	}
+	lines := []string{}
+	for _, c := range breakdown {
+		line := fmt.Sprintf("%s: %d firing", c.name, c.firing)
+		if c.resolved > 0 {
+			line += fmt.Sprintf(", %d resolved", c.resolved)
+		}
+		lines = append(lines, line)
+	}
+	if over {
+		lines = append(lines, "", "The storm is over, alerts are sent individually again.")
+	} else {
+		lines = append(lines, "", fmt.Sprintf("Alerts are summarized every %s until no more than %d arrive within that time.", s.window, s.threshold))
+	}
+	return GotifyNotification{Title: title, Message: strings.Join(lines, "\n"), Priority: s.priority}
+}
+
+// run sends the summaries of the collapsed alerts every window until the bridge exits.
+// Summaries that could not be sent are lost, the next one follows soon enough
+func (s *stormCollapse) run(svr *bridge) {
+	slog.Info("Collapsing alert storms", "threshold", s.threshold, "window", s.window)
+
+	for range time.Tick(s.window) {
+		s.flush(svr)
+	}
+}
+
+// flush sends the summaries of the collapsed alerts, unless the bridge is paused or within
+// quiet hours. Summaries are held back just like alerts, by leaving the alerts collapsed for
+// the first summary after the pause or quiet hours, and dropped when alerts would be dropped
+func (s *stormCollapse) flush(svr *bridge) {
+	logger := slog.With("storm", true)
+	if svr.pause.active() {
+		if !svr.pause.drop {
+			logger.Debug("Storm summary held while paused")
+			return
+		}
+		logger.Info("Storm summary dropped while paused", "summaries", len(s.summaries()))
+		return
+	}
+	if svr.quietHours.suppresses(s.priority) {
+		if svr.quietHours.queue {
+			logger.Debug("Storm summary held for quiet hours", "priority", s.priority)
+			return
+		}
+		logger.Info("Storm summary suppressed for quiet hours", "priority", s.priority, "summaries", len(s.summaries()))
+		return
+	}
+
+	for _, summary := range s.summaries() {
+		if *summary.svr.dryRun {
+			summary.svr.dryRunResult(logger, "Storm summary", summary.outbound)
+			continue
+		}
+		statusCode, status, _, err := summary.svr.dispatch(context.Background(), logger, summary.token, summary.outbound)
+		if err != nil || statusCode != 200 {
+			logger.Error("Unable to send storm summary to gotify", "status", status, "error", err)
+		}
+	}
+}
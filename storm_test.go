@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func stormTestAlert(name string, status string) Alert {
+	return Alert{Status: status, Labels: map[string]string{"alertname": name}}
+}
+
+func TestStormCollapses(t *testing.T) {
+	s, _ := newStormCollapse(2, time.Minute, 8)
+	svr := &bridge{}
+
+	for i, want := range []bool{false, false, true, true} {
+		if got := s.collapses(svr, stormTestAlert("Disk", "firing"), "token"); got != want {
+			t.Errorf("alert %d collapsed = %v, want %v", i+1, got, want)
+		}
+	}
+
+	summaries := s.summaries()
+	if len(summaries) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(summaries))
+	}
+	if got := summaries[0].outbound; got.Title != "2 alerts firing across 1 alertnames" || got.Priority != 8 {
+		t.Errorf("summary = %+v", got)
+	}
+
+	/* The arrivals are still within the window, so the storm goes on */
+	if !s.collapses(svr, stormTestAlert("Disk", "resolved"), "token") {
+		t.Error("storm ended while alerts keep arriving")
+	}
+}
+
+func TestStormSummaryPerDestination(t *testing.T) {
+	s, _ := newStormCollapse(0, time.Minute, 8)
+	primary, endpoint := &bridge{}, &bridge{}
+
+	s.collapses(primary, stormTestAlert("Disk", "firing"), "default")
+	s.collapses(endpoint, stormTestAlert("CPU", "firing"), "default")
+	s.collapses(primary, stormTestAlert("Disk", "resolved"), "default")
+	s.collapses(primary, stormTestAlert("Memory", "firing"), "oncall")
+
+	summaries := s.summaries()
+	if len(summaries) != 3 {
+		t.Fatalf("got %d summaries, want 3", len(summaries))
+	}
+	want := []struct {
+		svr   *bridge
+		token string
+		title string
+	}{
+		{primary, "default", "1 alerts firing and 1 resolved across 1 alertnames"},
+		{endpoint, "default", "1 alerts firing across 1 alertnames"},
+		{primary, "oncall", "1 alerts firing across 1 alertnames"},
+	}
+	for i, w := range want {
+		got := summaries[i]
+		if got.svr != w.svr || got.token != w.token || got.outbound.Title != w.title {
+			t.Errorf("summary %d = %s with %q, want %s with %q", i, got.outbound.Title, got.token, w.title, w.token)
+		}
+	}
+	if !strings.Contains(summaries[0].outbound.Message, "Disk: 1 firing, 1 resolved") {
+		t.Errorf("summary message %q lacks the breakdown", summaries[0].outbound.Message)
+	}
+}
+
+func TestStormFlushGating(t *testing.T) {
+	allDay, _ := parseQuietHours([]string{"00:00-24:00"}, "UTC", 9, "queue")
+	allDaySuppress, _ := parseQuietHours([]string{"00:00-24:00"}, "UTC", 9, "suppress")
+	belowStorm, _ := parseQuietHours([]string{"00:00-24:00"}, "UTC", 5, "suppress")
+
+	tests := []struct {
+		name      string
+		paused    bool
+		drop      bool
+		quiet     *quietHours
+		sent      int
+		collapsed int
+	}{
+		{name: "sent", sent: 1},
+		{name: "held while paused", paused: true, collapsed: 1},
+		{name: "dropped while paused", paused: true, drop: true},
+		{name: "held for quiet hours", quiet: allDay, collapsed: 1},
+		{name: "suppressed for quiet hours", quiet: allDaySuppress},
+		{name: "above quiet hours", quiet: belowStorm, sent: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotify := newFakeGotify(t, 200, 1)
+			svr := newDispatchTestBridge(gotify.URL)
+			svr.pause = newPauseState(tt.drop, 0, NewBridgeInstruments("test").paused)
+			if tt.paused {
+				svr.pause.pause(0)
+			}
+			svr.quietHours = tt.quiet
+
+			s, _ := newStormCollapse(0, time.Minute, 8)
+			s.collapses(svr, stormTestAlert("Disk", "firing"), "endpoint-token")
+			s.flush(svr)
+
+			if got := gotify.received(); len(got) != tt.sent || (tt.sent > 0 && got[0] != "endpoint-token") {
+				t.Errorf("gotify received %v, want %d summaries with endpoint-token", got, tt.sent)
+			}
+			if len(s.collapsed) != tt.collapsed {
+				t.Errorf("%d alerts left collapsed, want %d", len(s.collapsed), tt.collapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	ut "text/template"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+/* Editors write files in several steps, so changes are collected for this long before reloading */
+const templateReloadDelay = 500 * time.Millisecond
+
+// templateStore holds the user-defined templates. They may be replaced at any time while
+// alerts are rendered, e.g. when a template file was edited
+type templateStore struct {
+	value atomic.Pointer[ut.Template]
+}
+
+func newTemplateStore(tmpl *ut.Template) *templateStore {
+	s := &templateStore{}
+	s.value.Store(tmpl)
+	return s
+}
+
+// Get returns the current templates, nil when there are none
+func (s *templateStore) Get() *ut.Template {
+	if s == nil {
+		return nil
+	}
+	return s.value.Load()
+}
+
+// watch parses the templates below path again whenever a file in it changes. When the changed
+// templates have errors, the last working templates stay in use
+func (s *templateStore) watch(path string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = watchDirs(watcher, path); err != nil {
+		watcher.Close()
+		return err
+	}
+	slog.Info("Watching user-defined templates for changes", "path", path)
+
+	go func() {
+		var reload <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				slog.Debug("Template change detected", "file", event.Name, "op", event.Op.String())
+				reload = time.After(templateReloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Error watching user-defined templates", "error", err)
+			case <-reload:
+				reload = nil
+				/* New directories must be watched as well */
+				if err := watchDirs(watcher, path); err != nil {
+					slog.Warn("Unable to watch template directories", "error", err)
+				}
+
+				tmpl, err := parseUserTemplates(path)
+				if err != nil {
+					slog.Error("Unable to reload user-defined templates - keeping the current templates", "error", err)
+					continue
+				}
+				s.value.Store(tmpl)
+				slog.Info("Reloaded user-defined templates", "path", path)
+			}
+		}
+	}()
+	return nil
+}
+
+/* Watches path and every directory below it. Adding an already watched directory is a no-op */
+func watchDirs(watcher *fsnotify.Watcher, path string) error {
+	return filepath.Walk(path, func(dir string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(dir)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/* How often a token file is checked for a rotated token */
+const tokenFileInterval = 10 * time.Second
+
+// tokenSource holds the default Gotify application token. The token may be replaced at any
+// time while requests are handled, e.g. when the token file is rotated
+type tokenSource struct {
+	value atomic.Pointer[string]
+}
+
+func newTokenSource(token string) *tokenSource {
+	t := &tokenSource{}
+	t.Set(token)
+	return t
+}
+
+func (t *tokenSource) Get() string {
+	return *t.value.Load()
+}
+
+func (t *tokenSource) Set(token string) {
+	t.value.Store(&token)
+}
+
+func readTokenFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token file %s: %w", path, err)
+	}
+
+	token := string(bytes.TrimSpace(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// watchTokenFile re-reads the token file periodically and updates the token when it changed.
+// A file that can't be read keeps the last known token in use
+func (t *tokenSource) watchTokenFile(path string) {
+	for range time.Tick(tokenFileInterval) {
+		token, err := readTokenFile(path)
+		if err != nil {
+			slog.Warn("Unable to reload the Gotify token - keeping the current token", "error", err)
+			continue
+		}
+
+		if token != t.Get() {
+			slog.Info("Gotify token changed - using the new token", "path", path)
+			t.Set(token)
+		}
+	}
+}
+
+// requestToken returns the Gotify application token a webhook request carries and where it was
+// found. Headers are preferred over the token query parameter, as URLs end up in the access
+// logs of proxies. The Authorization header holds the JWT when --jwt_jwks_url is set
+func (svr *bridge) requestToken(r *http.Request) (string, string) {
+	if token := strings.TrimSpace(r.Header.Get("X-Gotify-Key")); token != "" {
+		return token, "X-Gotify-Key header"
+	}
+	if header := r.Header.Get("Authorization"); svr.jwt == nil && len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		if token := strings.TrimSpace(header[7:]); token != "" {
+			return token, "Authorization header"
+		}
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token, "token query parameter"
+	}
+	return "", ""
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// newGotifyTransport creates the transport shared by all requests to gotify, keeping up to
+// maxIdleConns connections per server open so bursts of alerts don't pay for a new connection
+// each. Requests go through proxyURL when it is set and otherwise honor $HTTP_PROXY,
+// $HTTPS_PROXY and $NO_PROXY. Certificates are verified against caFile in addition to the
+// system roots, if given
+func newGotifyTransport(proxyURL string, caFile string, insecureSkipVerify bool, maxIdleConns int) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.MaxIdleConnsPerHost = maxIdleConns
+	transport.MaxIdleConns = 0
+	transport.IdleConnTimeout = 90 * time.Second
+
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s'", proxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig, err := clientTLSConfig(caFile, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// clientTLSConfig trusts the certificates of caFile in addition to the system CAs
+func clientTLSConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %s: %w", caFile, err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
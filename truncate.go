@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const truncatedMarker = "… truncated"
+
+// truncateMessage shortens a message to at most max characters. The header at the start of the
+// message, such as the status line of --extended_details, and the footers, such as links to the
+// generator or runbook, are kept while the text between them is cut and marked as truncated.
+// It returns whether the message was shortened. Messages are not limited when max is 0
+func truncateMessage(message string, max int, header string, footers []string) (string, bool) {
+	if max <= 0 || utf8.RuneCountInString(message) <= max {
+		return message, false
+	}
+
+	body := strings.TrimPrefix(message, header)
+	if len(body) == len(message) {
+		header = ""
+	}
+
+	kept := []string{}
+	for i := len(footers) - 1; i >= 0; i-- {
+		if idx := strings.LastIndex(body, footers[i]); idx >= 0 {
+			body = body[:idx] + body[idx+len(footers[i]):]
+			kept = append([]string{footers[i]}, kept...)
+		}
+	}
+	footer := strings.Join(kept, "")
+
+	budget := max - utf8.RuneCountInString(header) - utf8.RuneCountInString(footer) - utf8.RuneCountInString(truncatedMarker)
+	if budget < 0 {
+		/* Not even the header and footers fit - cut the whole message instead */
+		header, footer, body = "", "", message
+		budget = max - utf8.RuneCountInString(truncatedMarker)
+		if budget < 0 {
+			budget = 0
+		}
+	}
+
+	runes := []rune(body)
+	if len(runes) > budget {
+		body = string(runes[:budget])
+	}
+	return header + strings.TrimRight(body, " \n") + truncatedMarker + footer, true
+}
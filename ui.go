@@ -0,0 +1,44 @@
+package main
+
+import (
+	_ "embed"
+	"log/slog"
+	"net/http"
+)
+
+//go:embed ui.html
+var uiPage []byte
+
+type healthStatus struct {
+	Up     bool              `json:"up"`
+	Status map[string]string `json:"status"`
+}
+
+// handleUI serves the dashboard, a single page polling the admin API
+func (svr *bridge) handleUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(uiPage)
+}
+
+// handleHealth returns whether gotify is reachable and the health it reports, as probed last
+func (svr *bridge) handleHealth(w http.ResponseWriter, r *http.Request) {
+	up, status := svr.cachedGotifyHealth()
+	writeJSON(w, healthStatus{Up: up, Status: status})
+}
+
+// handleTest sends a firing test alert to gotify on POST, the same one the send-test command sends
+func (svr *bridge) handleTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	messageID, err := svr.sendTestAlert("firing")
+	if err != nil {
+		slog.Warn("Test alert from the web UI failed", "error", err)
+		w.WriteHeader(http.StatusBadGateway)
+		writeJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, map[string]int{"message_id": messageID})
+}
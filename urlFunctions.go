@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// urlQuery escapes a value for the query of a URL. It takes the arguments of the built-in
+// urlquery of Go templates, so templates written for it keep working
+func urlQuery(args ...interface{}) string {
+	return url.QueryEscape(fmt.Sprint(args...))
+}
+
+// urlJoin appends path elements to a base URL, escaping each element and leaving the query of
+// the base alone, e.g. a dashboard URL with the instance of an alert
+func urlJoin(base string, elements ...string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("urlJoin: invalid base URL: %w", err)
+	}
+	/* Escaped elements stay a single segment of the path, even when they hold a slash */
+	escaped := make([]string, len(elements))
+	for i, element := range elements {
+		escaped[i] = url.PathEscape(element)
+	}
+	return u.JoinPath(escaped...).String(), nil
+}
+
+/* The counterpart of urlquery for values taken from URLs, e.g. query parameters of links */
+func urlDecode(s string) (string, error) {
+	return url.QueryUnescape(s)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AlertValue is one sample of the value string Grafana sends with its alerts, e.g.
+// [ var='B0' metric='up' labels={instance=localhost:9090, job=prometheus} value=0 ]
+type AlertValue struct {
+	Var    string
+	Metric string
+	Labels map[string]string
+	Value  float64
+}
+
+/* A comma only ends an unquoted label value when the next label starts right after it */
+var nextLabel = regexp.MustCompile(`^,\s*[a-zA-Z_][a-zA-Z0-9_]*=`)
+
+// Values parses the value string of the alert into its samples. An alert without a value
+// string has no samples
+func (a Alert) Values() ([]AlertValue, error) {
+	return parseValueString(a.ValueString)
+}
+
+// Humanize rounds a float to two decimals and strips trailing zeros
+func (a Alert) Humanize(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// ValueOf returns the value of the sample whose var or, failing that, metric has the given
+// name, e.g. {{ .ValueOf "B0" }}. Fails when the value string has no such sample
+func (a Alert) ValueOf(name string) (float64, error) {
+	values, err := parseValueString(a.ValueString)
+	if err != nil {
+		return 0, err
+	}
+	for _, value := range values {
+		if value.Var == name {
+			return value.Value, nil
+		}
+	}
+	for _, value := range values {
+		if value.Metric == name {
+			return value.Value, nil
+		}
+	}
+	return 0, fmt.Errorf("the value string has no sample named '%s'", name)
+}
+
+/* Units of humanizeBytes, each 1024 times the one before */
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// humanizeBytes formats a number of bytes with IEC units and a single decimal, e.g. 3.4 GiB.
+// It takes numbers as well as the strings of labels and annotations
+func humanizeBytes(i interface{}) (string, error) {
+	v, err := convertToFloat(i)
+	if err != nil {
+		return "", err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprintf("%.4g", v), nil
+	}
+	unit := 0
+	for math.Abs(v) >= 1024 && unit < len(byteUnits)-1 {
+		v /= 1024
+		unit++
+	}
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64) + " " + byteUnits[unit], nil
+}
+
+type valueParser struct {
+	s   string
+	pos int
+}
+
+// parseValueString parses the samples of a Grafana value string. Label values may be quoted
+// with ' or " and use backslash escapes, or be left unquoted - in which case they may contain
+// commas and equals signs as long as they are not followed by the next label
+func parseValueString(s string) ([]AlertValue, error) {
+	p := &valueParser{s: s}
+	values := []AlertValue{}
+
+	p.skipSpaces()
+	for !p.done() {
+		if len(values) > 0 {
+			if err := p.expect(','); err != nil {
+				return values, err
+			}
+			p.skipSpaces()
+		}
+
+		value, err := p.sample()
+		if err != nil {
+			return values, err
+		}
+		values = append(values, value)
+		p.skipSpaces()
+	}
+	return values, nil
+}
+
+func (p *valueParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *valueParser) skipSpaces() {
+	for !p.done() && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *valueParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid value string at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *valueParser) expect(c byte) error {
+	if p.done() || p.s[p.pos] != c {
+		return p.errorf("expected '%c'", c)
+	}
+	p.pos++
+	return nil
+}
+
+/* Reads a key of a sample or the name of a label */
+func (p *valueParser) name() (string, error) {
+	start := p.pos
+	for !p.done() {
+		c := p.s[p.pos]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (p.pos == start || c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a name")
+	}
+	return p.s[start:p.pos], nil
+}
+
+/* Reads a value enclosed in ' or " in which a backslash escapes the next character */
+func (p *valueParser) quoted() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && !p.done():
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated quoted value")
+}
+
+func (p *valueParser) isQuote() bool {
+	return !p.done() && (p.s[p.pos] == '\'' || p.s[p.pos] == '"')
+}
+
+/* Reads an unquoted label value up to the next label or the end of the labels */
+func (p *valueParser) bareLabelValue() string {
+	start := p.pos
+	for ; !p.done(); p.pos++ {
+		switch p.s[p.pos] {
+		case '}':
+			if rest := strings.TrimLeft(p.s[p.pos+1:], " "); rest == "" || rest[0] == ']' || nextKey(rest) {
+				return p.s[start:p.pos]
+			}
+		case ',':
+			if nextLabel.MatchString(p.s[p.pos:]) {
+				return p.s[start:p.pos]
+			}
+		}
+	}
+	return p.s[start:p.pos]
+}
+
+/* Tells whether s continues with the next key=value pair of a sample */
+func nextKey(s string) bool {
+	p := &valueParser{s: s}
+	if _, err := p.name(); err != nil {
+		return false
+	}
+	return p.expect('=') == nil
+}
+
+func (p *valueParser) labels() (map[string]string, error) {
+	labels := map[string]string{}
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	for !p.done() && p.s[p.pos] != '}' {
+		if len(labels) > 0 {
+			if err := p.expect(','); err != nil {
+				return nil, err
+			}
+			p.skipSpaces()
+		}
+
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect('='); err != nil {
+			return nil, err
+		}
+
+		value := ""
+		if p.isQuote() {
+			if value, err = p.quoted(); err != nil {
+				return nil, err
+			}
+		} else {
+			value = p.bareLabelValue()
+		}
+		labels[name] = value
+		p.skipSpaces()
+	}
+	return labels, p.expect('}')
+}
+
+func (p *valueParser) sample() (AlertValue, error) {
+	value := AlertValue{Labels: map[string]string{}}
+	if err := p.expect('['); err != nil {
+		return value, err
+	}
+
+	p.skipSpaces()
+	for !p.done() && p.s[p.pos] != ']' {
+		key, err := p.name()
+		if err != nil {
+			return value, err
+		}
+		if err = p.expect('='); err != nil {
+			return value, err
+		}
+
+		if key == "labels" {
+			if value.Labels, err = p.labels(); err != nil {
+				return value, err
+			}
+			p.skipSpaces()
+			continue
+		}
+
+		var raw string
+		if p.isQuote() {
+			if raw, err = p.quoted(); err != nil {
+				return value, err
+			}
+		} else {
+			start := p.pos
+			for !p.done() && p.s[p.pos] != ' ' && p.s[p.pos] != ']' {
+				p.pos++
+			}
+			raw = p.s[start:p.pos]
+		}
+
+		switch key {
+		case "var":
+			value.Var = raw
+		case "metric":
+			value.Metric = raw
+		case "value":
+			if value.Value, err = strconv.ParseFloat(raw, 64); err != nil {
+				return value, p.errorf("value '%s' is not a number", raw)
+			}
+		}
+		p.skipSpaces()
+	}
+	return value, p.expect(']')
+}
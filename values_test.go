@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseValueString(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []AlertValue
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  []AlertValue{},
+		},
+		{
+			name:  "single sample",
+			input: "[ var='B0' metric='up' labels={instance=localhost:9090, job=prometheus} value=0 ]",
+			want: []AlertValue{{
+				Var:    "B0",
+				Metric: "up",
+				Labels: map[string]string{"instance": "localhost:9090", "job": "prometheus"},
+				Value:  0,
+			}},
+		},
+		{
+			name:  "several samples",
+			input: "[ var='A' labels={} value=1.5 ], [ var='B' labels={} value=-2 ]",
+			want: []AlertValue{
+				{Var: "A", Labels: map[string]string{}, Value: 1.5},
+				{Var: "B", Labels: map[string]string{}, Value: -2},
+			},
+		},
+		{
+			name:  "unquoted label value with comma and equals sign",
+			input: "[ var='A' labels={query=a=1, b, job=x} value=3 ]",
+			want: []AlertValue{{
+				Var:    "A",
+				Labels: map[string]string{"query": "a=1, b", "job": "x"},
+				Value:  3,
+			}},
+		},
+		{
+			name:  "unquoted label value with closing brace",
+			input: "[ var='A' labels={path=/{id}} value=3 ]",
+			want: []AlertValue{{
+				Var:    "A",
+				Labels: map[string]string{"path": "/{id}"},
+				Value:  3,
+			}},
+		},
+		{
+			name:  "quoted label values with escapes",
+			input: `[ var="A" labels={msg='it\'s down', other="a,b"} value=1 ]`,
+			want: []AlertValue{{
+				Var:    "A",
+				Labels: map[string]string{"msg": "it's down", "other": "a,b"},
+				Value:  1,
+			}},
+		},
+		{
+			name:    "value not a number",
+			input:   "[ var='A' labels={} value=abc ]",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			input:   "[ var='A labels={} value=1 ]",
+			wantErr: true,
+		},
+		{
+			name:    "missing closing bracket",
+			input:   "[ var='A' labels={} value=1",
+			wantErr: true,
+		},
+		{
+			name:    "missing separator",
+			input:   "[ var='A' value=1 ] [ var='B' value=2 ]",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseValueString(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseValueString(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseValueString(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueOf(t *testing.T) {
+	alert := Alert{ValueString: "[ var='B0' metric='up' labels={} value=0 ], [ var='C' metric='load' labels={} value=4.25 ]"}
+
+	/* Values are found by their variable or their metric */
+	if v, err := alert.ValueOf("B0"); err != nil || v != 0 {
+		t.Errorf("ValueOf(B0) = %v, %v, want 0", v, err)
+	}
+	if v, err := alert.ValueOf("C"); err != nil || v != 4.25 {
+		t.Errorf("ValueOf(C) = %v, %v, want 4.25", v, err)
+	}
+	if v, err := alert.ValueOf("load"); err != nil || v != 4.25 {
+		t.Errorf("ValueOf(load) = %v, %v, want 4.25", v, err)
+	}
+	if _, err := alert.ValueOf("missing"); err == nil {
+		t.Error("ValueOf(missing) found a value")
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	tests := []struct {
+		input   interface{}
+		want    string
+		wantErr bool
+	}{
+		{input: 0, want: "0 B"},
+		{input: 1023, want: "1023 B"},
+		{input: 1024, want: "1 KiB"},
+		{input: 1536.0, want: "1.5 KiB"},
+		{input: "3650722201", want: "3.4 GiB"},
+		{input: -2048, want: "-2 KiB"},
+		{input: "not a number", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := humanizeBytes(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("humanizeBytes(%v) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("humanizeBytes(%v) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+/* Vault tokens are renewed this long before they expire */
+const vaultRenewMargin = time.Minute
+
+// vaultClient reads the Gotify token from a Vault KV secret. It logs in with the Kubernetes
+// auth method when a role is set and uses $VAULT_TOKEN otherwise
+type vaultClient struct {
+	address  string
+	role     string
+	authPath string
+	jwtPath  string
+	client   *http.Client
+
+	token       string
+	tokenExpiry time.Time
+}
+
+type vaultResponse struct {
+	Data json.RawMessage `json:"data"`
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+func newVaultClient(address string, role string, authPath string) (*vaultClient, error) {
+	v := &vaultClient{
+		address:  strings.TrimSuffix(address, "/"),
+		role:     role,
+		authPath: strings.Trim(authPath, "/"),
+		jwtPath:  "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if role == "" {
+		v.token = os.Getenv("VAULT_TOKEN")
+		if v.token == "" {
+			return nil, fmt.Errorf("either --vault_role or $VAULT_TOKEN must be set to authenticate with Vault")
+		}
+	}
+	return v, nil
+}
+
+func (v *vaultClient) request(ctx context.Context, method string, path string, body interface{}) (*vaultResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, v.address+"/v1/"+strings.TrimPrefix(path, "/"), reader)
+	if err != nil {
+		return nil, err
+	}
+	if v.token != "" {
+		request.Header.Set("X-Vault-Token", v.token)
+	}
+
+	resp, err := v.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result vaultResponse
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON returned from vault: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("non-200 response from vault for %s: %s %s", path, resp.Status, strings.Join(result.Errors, ", "))
+	}
+	return &result, nil
+}
+
+// login authenticates with the Kubernetes auth method unless a still valid token is known
+func (v *vaultClient) login(ctx context.Context) error {
+	if v.role == "" || (v.token != "" && time.Now().Add(vaultRenewMargin).Before(v.tokenExpiry)) {
+		return nil
+	}
+
+	jwt, err := os.ReadFile(v.jwtPath)
+	if err != nil {
+		return fmt.Errorf("unable to read service account token: %w", err)
+	}
+
+	v.token = ""
+	result, err := v.request(ctx, "POST", "auth/"+v.authPath+"/login", map[string]string{
+		"role": v.role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to log in to vault: %w", err)
+	}
+	if result.Auth == nil || result.Auth.ClientToken == "" {
+		return fmt.Errorf("no token returned by vault login")
+	}
+
+	v.token = result.Auth.ClientToken
+	v.tokenExpiry = time.Now().Add(time.Duration(result.Auth.LeaseDuration) * time.Second)
+	slog.Debug("Logged in to vault", "lease_duration", result.Auth.LeaseDuration)
+	return nil
+}
+
+// readSecret returns a key of a KV secret. Both version 1 and version 2 (where the path
+// contains /data/) of the KV secrets engine are supported
+func (v *vaultClient) readSecret(ctx context.Context, path string, key string) (string, error) {
+	if err := v.login(ctx); err != nil {
+		return "", err
+	}
+
+	result, err := v.request(ctx, "GET", path, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var data map[string]interface{}
+	if err = json.Unmarshal(result.Data, &data); err != nil {
+		return "", fmt.Errorf("unexpected secret format at %s: %w", path, err)
+	}
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	value, ok := data[key].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("secret %s has no key %s", path, key)
+	}
+	return value, nil
+}
+
+// watchVault re-reads the secret periodically so a rotated token is picked up. The current
+// token stays in use while vault can't be reached
+func (t *tokenSource) watchVault(v *vaultClient, path string, key string, interval time.Duration) {
+	for range time.Tick(interval) {
+		token, err := v.readSecret(context.Background(), path, key)
+		if err != nil {
+			slog.Warn("Unable to refresh the Gotify token from vault - keeping the current token", "error", err)
+			continue
+		}
+
+		if token != t.Get() {
+			slog.Info("Gotify token in vault changed - using the new token", "path", path)
+			t.Set(token)
+		}
+	}
+}
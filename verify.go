@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// tokenCheck remembers whether gotify accepted the application token when it was last
+// verified. The bridge reports itself ready through /-/ready only while it did
+type tokenCheck struct {
+	valid atomic.Bool
+}
+
+// verifyToken checks whether gotify accepts an application token. Application tokens can't
+// read anything from the gotify API, so an empty message is posted: gotify rejects an
+// unknown token with 401 or 403, while a valid token gets 400 for the missing message and
+// nothing is created
+func (svr *bridge) verifyToken(ctx context.Context, token string) error {
+	request, err := http.NewRequestWithContext(ctx, "POST", *svr.gotifyEndpoint, bytes.NewBufferString("{}"))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Gotify-Key", token)
+
+	resp, err := svr.gotifyClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to reach gotify: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusBadRequest:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("gotify rejected the application token: %s", resp.Status)
+	default:
+		return fmt.Errorf("unexpected response from gotify: %s", resp.Status)
+	}
+}
+
+// applicationName looks up the name of the gotify application a token belongs to. Listing
+// applications needs the client token, so the name is empty without one
+func (svr *bridge) applicationName(ctx context.Context, token string) (string, error) {
+	if svr.gotifyClientToken == nil || *svr.gotifyClientToken == "" {
+		return "", nil
+	}
+
+	apps, err := svr.listApplications(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, app := range apps {
+		if app.Token == token {
+			return app.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// checkToken verifies the default token and records the result. The application name is
+// logged the first time the token is found valid or after it was rejected
+func (svr *bridge) checkToken(ctx context.Context) error {
+	token := svr.gotifyToken.Get()
+	err := svr.verifyToken(ctx, token)
+	wasValid := svr.tokenCheck.valid.Swap(err == nil)
+	if err != nil || wasValid {
+		return err
+	}
+
+	name, nameErr := svr.applicationName(ctx, token)
+	if nameErr != nil {
+		slog.Debug("Unable to look up the name of the Gotify application", "error", nameErr)
+	}
+	if name != "" {
+		slog.Info("Gotify accepted the application token", "application", name)
+	} else {
+		slog.Info("Gotify accepted the application token")
+	}
+	return nil
+}
+
+// watchToken verifies the token again at the given interval, so a revoked or rotated token
+// is noticed before alerts fail
+func (svr *bridge) watchToken(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := svr.checkToken(context.Background()); err != nil {
+			slog.Warn("Verifying the Gotify token failed", "error", err)
+		}
+	}
+}
+
+// handleReady answers 200 while gotify accepts the token and 503 otherwise
+func (svr *bridge) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !svr.tokenCheck.valid.Load() {
+		http.Error(w, "Gotify token not verified", http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, "Ready", http.StatusOK)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// watchdog is a dead man's switch on the watchdog alert Prometheus fires all the time. When
+// no watchdog alert arrived within the timeout, the alerting pipeline is assumed broken and
+// gotify is told so by the bridge itself
+type watchdog struct {
+	spec     string
+	matcher  matcherSet
+	timeout  time.Duration
+	priority int
+
+	mu     sync.Mutex
+	last   time.Time
+	broken bool
+}
+
+func newWatchdog(spec string, timeout time.Duration, priority int) (*watchdog, error) {
+	matcher, err := parseMatcherSet(spec)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("the watchdog timeout must be positive")
+	}
+
+	/* The bridge just started - give the watchdog alert one timeout to show up */
+	return &watchdog{spec: spec, matcher: matcher, timeout: timeout, priority: priority, last: time.Now()}, nil
+}
+
+// isHeartbeat reports whether the alert is a firing watchdog alert. It is false for all
+// alerts when no watchdog is configured
+func (w *watchdog) isHeartbeat(alert Alert) bool {
+	return w != nil && alert.Status == "firing" && w.matcher.matches(alert.Labels)
+}
+
+func (w *watchdog) beat() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.last = time.Now()
+}
+
+// check returns the notification to send when the pipeline broke or recovered since the last
+// notification, along with the new state of the pipeline. changed is false when there is
+// nothing to send
+func (w *watchdog) check(svr *bridge) (outbound GotifyNotification, broken bool, changed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	silence := time.Since(w.last)
+	switch {
+	case !w.broken && silence > w.timeout:
+		return GotifyNotification{
+			Title:    "Alerting pipeline broken",
+			Message:  fmt.Sprintf("No watchdog alert matching %s was received for %s. Prometheus or Alertmanager may be down, so alerts may not reach Gotify.", w.spec, silence.Round(time.Second)),
+			Priority: w.priority,
+		}, true, true
+	case w.broken && silence <= w.timeout:
+		return GotifyNotification{
+			Title:    "Alerting pipeline restored",
+			Message:  fmt.Sprintf("The watchdog alert matching %s is received again.", w.spec),
+			Priority: *svr.defaultPriority,
+		}, false, true
+	}
+	return GotifyNotification{}, w.broken, false
+}
+
+func (w *watchdog) setBroken(broken bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.broken = broken
+}
+
+// run checks the watchdog until the bridge exits. A notification that could not be sent is
+// retried on the next check
+func (w *watchdog) run(svr *bridge) {
+	interval := w.timeout / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	slog.Info("Watching for the watchdog alert", "matcher", w.spec, "timeout", w.timeout)
+
+	for range time.Tick(interval) {
+		/* Prometheus or Alertmanager may well be down for the maintenance */
+		if svr.pause.active() {
+			continue
+		}
+
+		outbound, broken, changed := w.check(svr)
+		if !changed {
+			continue
+		}
+
+		logger := slog.With("watchdog", w.spec)
+		logger.Warn(outbound.Title)
+		if *svr.dryRun {
+			svr.dryRunResult(logger, "Watchdog message", outbound)
+			w.setBroken(broken)
+			continue
+		}
+
+		statusCode, status, _, err := svr.dispatch(context.Background(), logger, svr.gotifyToken.Get(), outbound)
+		if err != nil || statusCode != 200 {
+			logger.Error("Unable to send watchdog message to gotify - retrying", "status", status, "error", err)
+			continue
+		}
+		w.setBroken(broken)
+	}
+}
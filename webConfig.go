@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/exporter-toolkit/web"
+)
+
+// kitLogger passes the log lines of the exporter toolkit, which logs through go-kit, to slog
+type kitLogger struct{}
+
+func (kitLogger) Log(keyvals ...interface{}) error {
+	level := slog.LevelInfo
+	message := ""
+	attrs := []any{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		switch key {
+		case "level":
+			switch fmt.Sprint(keyvals[i+1]) {
+			case "debug":
+				level = slog.LevelDebug
+			case "warn":
+				level = slog.LevelWarn
+			case "error":
+				level = slog.LevelError
+			}
+		case "msg":
+			message = fmt.Sprint(keyvals[i+1])
+		default:
+			attrs = append(attrs, key, keyvals[i+1])
+		}
+	}
+	slog.Log(context.Background(), level, message, attrs...)
+	return nil
+}
+
+// serveWeb serves on the listener, with TLS and basic auth as configured in the web config
+// file of --web_config_file, in the format of the Prometheus exporter toolkit. Certificates and
+// users are read again for every connection, so they can be changed without a restart
+func serveWeb(server *http.Server, listener net.Listener, configFile string) error {
+	systemdSocket := false
+	addresses := []string{listener.Addr().String()}
+	flags := &web.FlagConfig{WebListenAddresses: &addresses, WebSystemdSocket: &systemdSocket, WebConfigFile: &configFile}
+	return web.Serve(listener, server, flags, kitLogger{})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// zabbixEvent holds the parameters of the webhook media type of Zabbix documented in the
+// README. Each is a macro Zabbix expands before running the webhook script
+type zabbixEvent struct {
+	EventID            string          `json:"event_id"`
+	EventName          string          `json:"event_name"`
+	EventValue         string          `json:"event_value"`
+	EventSeverity      string          `json:"event_severity"`
+	EventDate          string          `json:"event_date"`
+	EventTime          string          `json:"event_time"`
+	EventRecoveryDate  string          `json:"event_recovery_date"`
+	EventRecoveryTime  string          `json:"event_recovery_time"`
+	EventOpdata        string          `json:"event_opdata"`
+	EventTags          json.RawMessage `json:"event_tags"`
+	HostName           string          `json:"host_name"`
+	HostIP             string          `json:"host_ip"`
+	TriggerID          string          `json:"trigger_id"`
+	TriggerDescription string          `json:"trigger_description"`
+	ZabbixURL          string          `json:"zabbix_url"`
+}
+
+type zabbixTag struct {
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+/* Macros Zabbix could not resolve are sent as they are, e.g. {EVENT.RECOVERY.DATE} of problems */
+var unresolvedMacro = regexp.MustCompile(`^\{[A-Z0-9_.$]+\}$`)
+
+func zabbixValue(s string) string {
+	s = strings.TrimSpace(s)
+	if unresolvedMacro.MatchString(s) || s == "*UNKNOWN*" {
+		return ""
+	}
+	return s
+}
+
+// parseZabbix converts a problem or recovery event of Zabbix into an alert named after the
+// event, labeled with the severity, host and tags of the event
+func parseZabbix(svr *bridge, body []byte) (Notification, error) {
+	var event zabbixEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return Notification{}, fmt.Errorf("invalid Zabbix event: %w", err)
+	}
+	eventID := zabbixValue(event.EventID)
+	name := zabbixValue(event.EventName)
+	if eventID == "" || name == "" {
+		return Notification{}, fmt.Errorf("invalid Zabbix event: event_id and event_name are required")
+	}
+
+	alert := Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": name, "zabbix_event_id": eventID},
+		Annotations: map[string]string{},
+		Fingerprint: "zabbix-" + eventID,
+	}
+	/* EVENT.VALUE is 1 for problems and 0 for recoveries */
+	if zabbixValue(event.EventValue) == "0" {
+		alert.Status = "resolved"
+	}
+
+	if severity := zabbixValue(event.EventSeverity); severity != "" {
+		alert.Labels[*svr.severityLabel] = strings.ReplaceAll(strings.ToLower(severity), " ", "_")
+	}
+	if host := zabbixValue(event.HostName); host != "" {
+		alert.Labels["instance"] = host
+	}
+	if ip := zabbixValue(event.HostIP); ip != "" {
+		alert.Labels["host_ip"] = ip
+	}
+	tags, err := zabbixTags(event.EventTags)
+	if err != nil {
+		return Notification{}, err
+	}
+	for _, tag := range tags {
+		if _, taken := alert.Labels[tag.Tag]; !taken && tag.Tag != "" {
+			alert.Labels[tag.Tag] = tag.Value
+		}
+	}
+
+	description := zabbixValue(event.TriggerDescription)
+	if description == "" {
+		description = name
+		if host := alert.Labels["instance"]; host != "" && !strings.Contains(name, host) {
+			description = fmt.Sprintf("%s on %s", name, host)
+		}
+	}
+	if opdata := zabbixValue(event.EventOpdata); opdata != "" {
+		description += "\n\n" + opdata
+	}
+	alert.Annotations[*svr.titleAnnotation] = name
+	alert.Annotations[*svr.messageAnnotation] = description
+
+	if started, ok := zabbixTime(event.EventDate, event.EventTime); ok {
+		alert.StartsAt = started.Format(time.RFC3339)
+	}
+	if recovered, ok := zabbixTime(event.EventRecoveryDate, event.EventRecoveryTime); ok {
+		alert.EndsAt = recovered.Format(time.RFC3339)
+	}
+
+	zabbixURL := strings.TrimSuffix(zabbixValue(event.ZabbixURL), "/")
+	if triggerID := zabbixValue(event.TriggerID); zabbixURL != "" && triggerID != "" {
+		alert.GeneratorURL = fmt.Sprintf("%s/tr_events.php?triggerid=%s&eventid=%s", zabbixURL, url.QueryEscape(triggerID), url.QueryEscape(eventID))
+		alert.ExternalURL = zabbixURL
+	}
+
+	return singleAlert("zabbix", "zabbix:"+eventID, alert), nil
+}
+
+/* EVENT.TAGSJSON is a JSON array, which the webhook script may pass on as it is or as a string */
+func zabbixTags(raw json.RawMessage) ([]zabbixTag, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if text = zabbixValue(text); text == "" {
+			return nil, nil
+		}
+		raw = json.RawMessage(text)
+	}
+
+	var tags []zabbixTag
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return nil, fmt.Errorf("invalid event_tags of Zabbix event: %w", err)
+	}
+	return tags, nil
+}
+
+/* Zabbix sends dates as 2006.01.02 and times as 15:04:05 in the timezone of the server */
+func zabbixTime(date string, clock string) (time.Time, bool) {
+	date, clock = zabbixValue(date), zabbixValue(clock)
+	if date == "" || clock == "" {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation("2006.01.02 15:04:05", date+" "+clock, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
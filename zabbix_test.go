@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+/* A bridge with the default annotations and severity label, for the parsers of other tools */
+func newInputTestBridge() *bridge {
+	severity, title, message, priority := "severity", "title", "description", "priority"
+	return &bridge{severityLabel: &severity, titleAnnotation: &title, messageAnnotation: &message, priorityAnnotation: &priority}
+}
+
+/* Checks the single alert of a notification, ignoring StartsAt and EndsAt unless wanted */
+func checkInputAlert(t *testing.T, n Notification, want Alert) {
+	t.Helper()
+	if len(n.Alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(n.Alerts))
+	}
+	got := n.Alerts[0]
+	if want.StartsAt == "" && want.EndsAt == "" {
+		got.StartsAt, got.EndsAt = "", ""
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got alert %+v, want %+v", got, want)
+	}
+	if n.Status != want.Status {
+		t.Errorf("got notification status %q, want %q", n.Status, want.Status)
+	}
+}
+
+func TestParseZabbix(t *testing.T) {
+	started, _ := time.ParseInLocation("2006.01.02 15:04:05", "2024.03.01 10:15:00", time.Local)
+	recovered, _ := time.ParseInLocation("2006.01.02 15:04:05", "2024.03.01 10:45:30", time.Local)
+
+	tests := []struct {
+		name    string
+		body    string
+		want    Alert
+		wantErr bool
+	}{
+		{
+			name: "problem",
+			body: `{"event_id":"42","event_name":"High CPU","event_value":"1","event_severity":"High","event_date":"2024.03.01","event_time":"10:15:00",
+				"event_recovery_date":"{EVENT.RECOVERY.DATE}","event_recovery_time":"{EVENT.RECOVERY.TIME}","event_opdata":"CPU: 97%",
+				"event_tags":[{"tag":"service","value":"web"},{"tag":"instance","value":"ignored"}],"host_name":"web1","host_ip":"10.0.0.1",
+				"trigger_id":"7","trigger_description":"{TRIGGER.DESCRIPTION}","zabbix_url":"https://zabbix/"}`,
+			want: Alert{
+				Status:       "firing",
+				Fingerprint:  "zabbix-42",
+				Labels:       map[string]string{"alertname": "High CPU", "zabbix_event_id": "42", "severity": "high", "instance": "web1", "host_ip": "10.0.0.1", "service": "web"},
+				Annotations:  map[string]string{"title": "High CPU", "description": "High CPU on web1\n\nCPU: 97%"},
+				GeneratorURL: "https://zabbix/tr_events.php?triggerid=7&eventid=42",
+				ExternalURL:  "https://zabbix",
+				StartsAt:     started.Format(time.RFC3339),
+			},
+		},
+		{
+			name: "recovery",
+			body: `{"event_id":"42","event_name":"High CPU on web1","event_value":"0","event_severity":"Not classified","event_date":"2024.03.01","event_time":"10:15:00",
+				"event_recovery_date":"2024.03.01","event_recovery_time":"10:45:30","event_tags":"[{\"tag\":\"service\",\"value\":\"web\"}]","host_name":"web1",
+				"trigger_description":"CPU is back to normal"}`,
+			want: Alert{
+				Status:      "resolved",
+				Fingerprint: "zabbix-42",
+				Labels:      map[string]string{"alertname": "High CPU on web1", "zabbix_event_id": "42", "severity": "not_classified", "instance": "web1", "service": "web"},
+				Annotations: map[string]string{"title": "High CPU on web1", "description": "CPU is back to normal"},
+				StartsAt:    started.Format(time.RFC3339),
+				EndsAt:      recovered.Format(time.RFC3339),
+			},
+		},
+		{
+			name: "unresolved macros",
+			body: `{"event_id":"1","event_name":"Ping","event_value":"{EVENT.VALUE}","host_name":"*UNKNOWN*","event_tags":"{EVENT.TAGSJSON}"}`,
+			want: Alert{
+				Status:      "firing",
+				Fingerprint: "zabbix-1",
+				Labels:      map[string]string{"alertname": "Ping", "zabbix_event_id": "1"},
+				Annotations: map[string]string{"title": "Ping", "description": "Ping"},
+			},
+		},
+		{name: "missing event_id", body: `{"event_id":"{EVENT.ID}","event_name":"Ping"}`, wantErr: true},
+		{name: "missing event_name", body: `{"event_id":"1"}`, wantErr: true},
+		{name: "invalid tags", body: `{"event_id":"1","event_name":"Ping","event_tags":"not json"}`, wantErr: true},
+		{name: "not json", body: `event`, wantErr: true},
+	}
+
+	svr := newInputTestBridge()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := parseZabbix(svr, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseZabbix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				checkInputAlert(t, n, tt.want)
+			}
+		})
+	}
+}